@@ -5,6 +5,8 @@ import (
 	"errors"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -38,6 +40,12 @@ type App struct {
 	// 用于保护 instance 字段
 	mu       sync.Mutex
 	instance *registry.ServiceInstance
+
+	// 用于串行化元数据更新
+	updateMu sync.Mutex
+	stopped  bool
+	// registered reports whether the instance is registered by Run.
+	registered bool
 }
 
 // New create an application lifecycle manager.
@@ -89,7 +97,61 @@ func (a *App) Version() string { return a.opts.version }
 
 // Metadata returns service metadata.
 // 返回应用实例元数据
-func (a *App) Metadata() map[string]string { return a.opts.metadata }
+func (a *App) Metadata() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.opts.metadata
+}
+
+// UpdateMetadata merges the metadata into the app metadata at runtime,
+// and registers the updated instance through the registrar while running.
+// An empty value deletes the key.
+// 运行时更新元数据, 并通过注册中心重新注册实例
+func (a *App) UpdateMetadata(ctx context.Context, md map[string]string) error {
+	a.updateMu.Lock()
+	defer a.updateMu.Unlock()
+
+	a.mu.Lock()
+	metadata := make(map[string]string, len(a.opts.metadata)+len(md))
+	for k, v := range a.opts.metadata {
+		metadata[k] = v
+	}
+	for k, v := range md {
+		if v == "" {
+			delete(metadata, k)
+			continue
+		}
+		metadata[k] = v
+	}
+	a.opts.metadata = metadata
+	instance := a.instance
+	stopped := a.stopped
+	if instance != nil {
+		// copy on write, the registered instance may be in use
+		ins := *instance
+		ins.Metadata = metadata
+		instance = &ins
+		a.instance = instance
+	}
+	a.mu.Unlock()
+
+	if a.opts.registrar == nil || instance == nil || stopped {
+		return nil
+	}
+	rctx, cancel := context.WithTimeout(ctx, a.opts.registrarTimeout)
+	defer cancel()
+	return a.opts.registrar.Register(rctx, instance)
+}
+
+// SetWeight updates the weight of the instance, e.g. to ramp up the traffic after start.
+func (a *App) SetWeight(ctx context.Context, weight int64) error {
+	return a.UpdateMetadata(ctx, map[string]string{registry.WeightKey: strconv.FormatInt(weight, 10)})
+}
+
+// SetStatus updates the status of the instance, e.g. registry.StatusDraining.
+func (a *App) SetStatus(ctx context.Context, status string) error {
+	return a.UpdateMetadata(ctx, map[string]string{registry.StatusKey: status})
+}
 
 // Endpoint returns endpoints.
 func (a *App) Endpoint() []string {
@@ -101,7 +163,9 @@ func (a *App) Endpoint() []string {
 
 // Run executes all OnStart hooks registered with the application's Lifecycle.
 // Run 执行所有在应用程序生命周期中注册的 OnStart 钩子。
-func (a *App) Run() error {
+// 执行顺序: BeforeStart -> 启动服务 -> 注册实例 -> AfterStart,
+// 退出时: BeforeStop -> 注销实例 -> 等待 DrainDelay -> 停止服务 -> AfterStop。
+func (a *App) Run() (err error) {
 	instance, err := a.buildInstance()
 	if err != nil {
 		return err
@@ -111,7 +175,23 @@ func (a *App) Run() error {
 	a.instance = instance
 	a.mu.Unlock()
 
-	eg, ctx := errgroup.WithContext(NewContext(a.ctx, a))
+	// AfterStop hooks are always run, even if the app fails to start.
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(NewContext(a.opts.ctx, a), a.opts.stopTimeout)
+		defer cancel()
+		for _, fn := range a.opts.afterStop {
+			err = joinErrors(err, fn(stopCtx))
+		}
+	}()
+
+	sctx := NewContext(a.ctx, a)
+	for _, fn := range a.opts.beforeStart {
+		if err := fn(sctx); err != nil {
+			return err
+		}
+	}
+
+	eg, ctx := errgroup.WithContext(sctx)
 	wg := sync.WaitGroup{}
 
 	for _, srv := range a.opts.servers {
@@ -130,10 +210,22 @@ func (a *App) Run() error {
 	}
 	wg.Wait()
 	if a.opts.registrar != nil {
+		// the metadata may be updated by the hooks
+		a.mu.Lock()
+		instance = a.instance
+		a.mu.Unlock()
 		rctx, rcancel := context.WithTimeout(ctx, a.opts.registrarTimeout)
 		defer rcancel()
 		if err := a.opts.registrar.Register(rctx, instance); err != nil {
-			return err
+			return a.abort(eg, err)
+		}
+		a.mu.Lock()
+		a.registered = true
+		a.mu.Unlock()
+	}
+	for _, fn := range a.opts.afterStart {
+		if err := fn(sctx); err != nil {
+			return a.abort(eg, err)
 		}
 	}
 	c := make(chan os.Signal, 1)
@@ -150,29 +242,81 @@ func (a *App) Run() error {
 			}
 		}
 	})
-	if err := eg.Wait(); err != nil && !errors.Is(err, context.Canceled) {
-		return err
+	if err = eg.Wait(); errors.Is(err, context.Canceled) {
+		err = nil
 	}
-	return nil
+	return err
 }
 
-// Stop gracefully stops the application.
+// abort stops the started servers when the app fails to start,
+// and returns the start error joined with the stop errors.
+func (a *App) abort(eg *errgroup.Group, err error) error {
+	err = joinErrors(err, a.Stop())
+	if werr := eg.Wait(); !errors.Is(werr, context.Canceled) {
+		err = joinErrors(err, werr)
+	}
+	return err
+}
+
+// Stop gracefully stops the application, the servers are always stopped,
+// even if the instance fails to deregister.
+// The deregistration and the drain delay are skipped if the instance is never registered.
 // 优雅退出应用
-func (a *App) Stop() error {
+func (a *App) Stop() (err error) {
+	sctx, scancel := context.WithTimeout(NewContext(a.opts.ctx, a), a.opts.stopTimeout)
+	defer scancel()
+	for _, fn := range a.opts.beforeStop {
+		err = joinErrors(err, fn(sctx))
+	}
+	// no more registration by UpdateMetadata once deregistered
+	a.updateMu.Lock()
 	a.mu.Lock()
 	instance := a.instance
+	registered := a.registered
+	a.stopped = true
 	a.mu.Unlock()
-	if a.opts.registrar != nil && instance != nil {
+	if a.opts.registrar != nil && registered {
 		ctx, cancel := context.WithTimeout(NewContext(a.ctx, a), a.opts.registrarTimeout)
 		defer cancel()
-		if err := a.opts.registrar.Deregister(ctx, instance); err != nil {
-			return err
-		}
+		err = joinErrors(err, a.opts.registrar.Deregister(ctx, instance))
+	}
+	a.updateMu.Unlock()
+	if a.opts.registrar == nil || registered {
+		a.drain(sctx)
 	}
 	if a.cancel != nil {
 		a.cancel()
 	}
-	return nil
+	return err
+}
+
+// drain marks the servers as not serving and waits for the drain delay,
+// so that clients with cached instances stop sending traffic before the servers stop.
+func (a *App) drain(ctx context.Context) {
+	var drainers []transport.Drainer
+	for _, srv := range a.opts.servers {
+		if d, ok := srv.(transport.Drainer); ok {
+			if err := d.Drain(ctx); err != nil {
+				log.Errorf("failed to drain server: %v", err)
+				continue
+			}
+			drainers = append(drainers, d)
+		}
+	}
+	if a.opts.drainDelay <= 0 {
+		return
+	}
+	log.Infof("draining for %s before stopping servers", a.opts.drainDelay)
+	select {
+	case <-time.After(a.opts.drainDelay):
+	case <-ctx.Done():
+		log.Warnf("drain interrupted: %v", ctx.Err())
+	}
+	var inflight int64
+	for _, d := range drainers {
+		inflight += d.Inflight()
+	}
+	log.Infof("drain finished, %d requests in flight", inflight)
 }
 
 // 构建实例
@@ -199,11 +343,53 @@ func (a *App) buildInstance() (*registry.ServiceInstance, error) {
 		ID:        a.opts.id,
 		Name:      a.opts.name,
 		Version:   a.opts.version,
-		Metadata:  a.opts.metadata,
+		Metadata:  a.Metadata(),
 		Endpoints: endpoints,
 	}, nil
 }
 
+// multiError is the errors joined by joinErrors.
+type multiError []error
+
+func (m multiError) Error() string {
+	s := make([]string, 0, len(m))
+	for _, err := range m {
+		s = append(s, err.Error())
+	}
+	return strings.Join(s, "; ")
+}
+
+// Is reports whether any of the errors matches the target.
+func (m multiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinErrors returns the non-nil errors joined, or the only one as is.
+func joinErrors(errs ...error) error {
+	var m multiError
+	for _, err := range errs {
+		switch e := err.(type) {
+		case nil:
+		case multiError:
+			m = append(m, e...)
+		default:
+			m = append(m, e)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	}
+	return m
+}
+
 type appKey struct{}
 
 // NewContext returns a new Context that carries value.
@@ -2,6 +2,7 @@ package kratos
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sync"
@@ -56,6 +57,197 @@ func TestApp(t *testing.T) {
 	}
 }
 
+func TestApp_Hooks(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	hook := func(name string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			if _, ok := FromContext(ctx); !ok {
+				t.Errorf("%s: app info not found in context", name)
+			}
+			mu.Lock()
+			events = append(events, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	app := New(
+		Name("kratos"),
+		Server(&mockServer{}),
+		BeforeStart(hook("beforeStart")),
+		AfterStart(hook("afterStart")),
+		BeforeStop(hook("beforeStop")),
+		AfterStop(hook("afterStop")),
+	)
+	time.AfterFunc(100*time.Millisecond, func() {
+		_ = app.Stop()
+	})
+	if err := app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"beforeStart", "afterStart", "beforeStop", "afterStop"}
+	if !reflect.DeepEqual(expected, events) {
+		t.Fatalf("events:%v is not equal to %v", events, expected)
+	}
+}
+
+func TestApp_HookError(t *testing.T) {
+	want := fmt.Errorf("before start")
+	app := New(
+		Name("kratos"),
+		Server(&mockServer{}),
+		BeforeStart(func(context.Context) error { return want }),
+	)
+	if err := app.Run(); err != want {
+		t.Fatalf("err:%v is not equal to %v", err, want)
+	}
+	want = fmt.Errorf("after start")
+	app = New(
+		Name("kratos"),
+		Server(&mockServer{}),
+		AfterStart(func(context.Context) error { return want }),
+	)
+	if err := app.Run(); err != want {
+		t.Fatalf("err:%v is not equal to %v", err, want)
+	}
+}
+
+type mockStopServer struct {
+	stopped bool
+}
+
+func (m *mockStopServer) Start(ctx context.Context) error { return nil }
+func (m *mockStopServer) Stop(ctx context.Context) error {
+	m.stopped = true
+	return nil
+}
+
+func TestApp_AfterStartError(t *testing.T) {
+	var (
+		srv      = &mockStopServer{}
+		started  = fmt.Errorf("after start")
+		stopped  = fmt.Errorf("after stop")
+		afterRun bool
+	)
+	app := New(
+		Name("kratos"),
+		Server(srv),
+		AfterStart(func(context.Context) error { return started }),
+		AfterStop(func(context.Context) error {
+			afterRun = true
+			return stopped
+		}),
+	)
+	err := app.Run()
+	if !errors.Is(err, started) || !errors.Is(err, stopped) {
+		t.Errorf("expect %v and %v, got %v", started, stopped, err)
+	}
+	if !srv.stopped {
+		t.Error("expect the server to be stopped")
+	}
+	if !afterRun {
+		t.Error("expect the after stop hook to be run")
+	}
+}
+
+type failRegistry struct {
+	deregistered bool
+}
+
+func (r *failRegistry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	return fmt.Errorf("register")
+}
+
+func (r *failRegistry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	r.deregistered = true
+	return fmt.Errorf("deregister")
+}
+
+func TestApp_RegisterError(t *testing.T) {
+	var (
+		srv = &mockStopServer{}
+		r   = &failRegistry{}
+	)
+	app := New(Name("kratos"), Server(srv), Registrar(r), DrainDelay(time.Hour))
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expect the register error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect Run to return")
+	}
+	if !srv.stopped {
+		t.Error("expect the server to be stopped")
+	}
+	if r.deregistered {
+		t.Error("expect no deregistration of the instance never registered")
+	}
+}
+
+type mockDrainer struct {
+	mockServer
+	drained bool
+}
+
+func (m *mockDrainer) Drain(ctx context.Context) error {
+	m.drained = true
+	return nil
+}
+
+func (m *mockDrainer) Inflight() int64 { return 0 }
+
+func TestApp_Drain(t *testing.T) {
+	srv := &mockDrainer{}
+	r := &mockRegistry{service: make(map[string]*registry.ServiceInstance)}
+	app := New(
+		Name("kratos"),
+		Server(srv),
+		Registrar(r),
+		DrainDelay(50*time.Millisecond),
+		BeforeStop(func(context.Context) error {
+			if srv.drained {
+				t.Error("server is drained before deregistration")
+			}
+			return nil
+		}),
+	)
+	time.AfterFunc(100*time.Millisecond, func() {
+		_ = app.Stop()
+	})
+	if err := app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.drained {
+		t.Fatal("server is not drained")
+	}
+	if len(r.service) != 0 {
+		t.Fatal("service is not deregistered")
+	}
+}
+
+func TestApp_DrainTimeout(t *testing.T) {
+	app := New(
+		Name("kratos"),
+		Server(&mockDrainer{}),
+		DrainDelay(time.Hour),
+		StopTimeout(50*time.Millisecond),
+	)
+	start := time.Now()
+	if err := app.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expect the drain to be interrupted by the stop timeout, got %s", d)
+	}
+}
+
 func TestApp_ID(t *testing.T) {
 	v := "123"
 	o := New(ID(v))
@@ -91,6 +283,58 @@ func TestApp_Metadata(t *testing.T) {
 	}
 }
 
+func TestApp_UpdateMetadata(t *testing.T) {
+	r := &mockRegistry{service: make(map[string]*registry.ServiceInstance)}
+	app := New(
+		ID("1"),
+		Name("kratos"),
+		Metadata(map[string]string{"a": "1"}),
+		Registrar(r),
+	)
+	ctx := context.Background()
+	// not registered before running
+	if err := app.SetWeight(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.service) != 0 {
+		t.Fatalf("expect %v, got %v", 0, len(r.service))
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := app.SetStatus(ctx, registry.StatusDraining); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.UpdateMetadata(ctx, map[string]string{"a": ""}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{registry.WeightKey: "10", registry.StatusKey: registry.StatusDraining}
+	r.lk.Lock()
+	got := r.service["1"].Metadata
+	r.lk.Unlock()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expect %v, got %v", want, got)
+	}
+	if !reflect.DeepEqual(want, app.Metadata()) {
+		t.Errorf("expect %v, got %v", want, app.Metadata())
+	}
+	if err := app.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// not registered again after stopped
+	if err := app.SetWeight(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.service) != 0 {
+		t.Errorf("expect %v, got %v", 0, len(r.service))
+	}
+}
+
 func TestApp_Endpoint(t *testing.T) {
 	type fields struct {
 		id       string
@@ -5,11 +5,13 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -18,6 +20,7 @@ const (
 	contextPackage       = protogen.GoImportPath("context")
 	transportHTTPPackage = protogen.GoImportPath("github.com/go-kratos/kratos/v2/transport/http")
 	bindingPackage       = protogen.GoImportPath("github.com/go-kratos/kratos/v2/transport/http/binding")
+	timePackage          = protogen.GoImportPath("time")
 )
 
 var methodSets = make(map[string]int)
@@ -192,6 +195,7 @@ func buildMethodDesc(g *protogen.GeneratedFile, m *protogen.Method, method, path
 		}
 	}
 	return &methodDesc{
+		Timeout:      buildTimeout(g, m),
 		Name:         m.GoName,
 		OriginalName: string(m.Desc.Name()),
 		Num:          methodSets[m.GoName],
@@ -203,6 +207,69 @@ func buildMethodDesc(g *protogen.GeneratedFile, m *protogen.Method, method, path
 	}
 }
 
+// timeoutField is the field number of the kratos.api.timeout method option,
+// which is defined in third_party/kratos/api/annotations.proto.
+const timeoutField protowire.Number = 1110
+
+// buildTimeout returns the timeout declared by the method option as a Go expression,
+// it's "0" if no timeout is declared.
+func buildTimeout(g *protogen.GeneratedFile, m *protogen.Method) string {
+	v, ok := methodTimeout(m)
+	if !ok || v == "" {
+		return "0"
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		fmt.Fprintf(os.Stderr, "\u001B[31mERROR\u001B[m: The timeout '%s' of method '%s' is invalid.\n", v, m.Desc.FullName())
+		os.Exit(2)
+	}
+	switch {
+	case d%time.Second == 0:
+		return fmt.Sprintf("%d * %s", d/time.Second, g.QualifiedGoIdent(timePackage.Ident("Second")))
+	case d%time.Millisecond == 0:
+		return fmt.Sprintf("%d * %s", d/time.Millisecond, g.QualifiedGoIdent(timePackage.Ident("Millisecond")))
+	default:
+		return fmt.Sprintf("%d * %s", d, g.QualifiedGoIdent(timePackage.Ident("Nanosecond")))
+	}
+}
+
+// methodTimeout returns the timeout option of the method. The option is read from
+// the unknown fields, as the extension isn't registered in the generator, so that
+// the generator doesn't depend on the kratos module which defines it.
+func methodTimeout(m *protogen.Method) (string, bool) {
+	opts, ok := m.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return "", false
+	}
+	var (
+		v     string
+		found bool
+	)
+	b := opts.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return "", false
+		}
+		b = b[n:]
+		if num == timeoutField && typ == protowire.BytesType {
+			s, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return "", false
+			}
+			// the last one wins, as the proto decoding does
+			v, found = s, true
+			b = b[n:]
+			continue
+		}
+		if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+			return "", false
+		}
+		b = b[n:]
+	}
+	return v, found
+}
+
 func buildPathVars(path string) (res map[string]*string) {
 	if strings.HasSuffix(path, "/") {
 		fmt.Fprintf(os.Stderr, "\u001B[31mWARN\u001B[m: Path %s should not end with \"/\" \n", path)
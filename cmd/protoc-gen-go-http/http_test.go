@@ -3,6 +3,12 @@ package main
 import (
 	"reflect"
 	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
 )
 
 func TestNoParameters(t *testing.T) {
@@ -85,3 +91,51 @@ func TestIterationMiddle(t *testing.T) {
 		t.Fatal(`replacePath("message.name", "messages/*", path) should be "/test/{message.name:messages/.*}/books"`)
 	}
 }
+
+func TestBuildTimeout(t *testing.T) {
+	tests := []struct {
+		timeout string
+		expect  string
+	}{
+		{"", "0"},
+		{"5m", "300 * time.Second"},
+		{"1500ms", "1500 * time.Millisecond"},
+		{"500us", "500000 * time.Nanosecond"},
+	}
+	for _, test := range tests {
+		opts := &descriptorpb.MethodOptions{}
+		if test.timeout != "" {
+			// the option is encoded as it's sent by protoc.
+			b := protowire.AppendTag(nil, timeoutField, protowire.BytesType)
+			opts.ProtoReflect().SetUnknown(protowire.AppendString(b, test.timeout))
+		}
+		fd := &descriptorpb.FileDescriptorProto{
+			Name:        proto.String("test.proto"),
+			Package:     proto.String("test"),
+			Syntax:      proto.String("proto3"),
+			Options:     &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
+			MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Request")}},
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("Test"),
+				Method: []*descriptorpb.MethodDescriptorProto{{
+					Name:       proto.String("Export"),
+					InputType:  proto.String(".test.Request"),
+					OutputType: proto.String(".test.Request"),
+					Options:    opts,
+				}},
+			}},
+		}
+		gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"test.proto"},
+			ProtoFile:      []*descriptorpb.FileDescriptorProto{fd},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := gen.Files[0]
+		g := gen.NewGeneratedFile("test_http.pb.go", file.GoImportPath)
+		if v := buildTimeout(g, file.Services[0].Methods[0]); v != test.expect {
+			t.Errorf("expect %v, got %v", test.expect, v)
+		}
+	}
+}
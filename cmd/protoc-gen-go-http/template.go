@@ -24,6 +24,7 @@ func Register{{.ServiceType}}HTTPServer(s *http.Server, srv {{.ServiceType}}HTTP
 	r := s.Route("/")
 	{{- range .Methods}}
 	r.{{.Method}}("{{.Path}}", _{{$svrType}}_{{.Name}}{{.Num}}_HTTP_Handler(srv))
+	r.Operation("{{.Method}}", "{{.Path}}", Operation{{$svrType}}{{.OriginalName}}, {{.Timeout}})
 	{{- end}}
 }
 
@@ -121,6 +122,8 @@ type methodDesc struct {
 	HasBody      bool
 	Body         string
 	ResponseBody string
+	// the timeout declared by the method option
+	Timeout string
 }
 
 func (s *serviceDesc) execute() string {
@@ -12,6 +12,7 @@ import (
 
 var (
 	_ registry.Registrar = &Registry{}
+	_ registry.Checker   = &Registry{}
 	_ registry.Discovery = &Registry{}
 )
 
@@ -102,6 +103,16 @@ func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInst
 	return err
 }
 
+// Registered reports whether the key of the instance exists, it's deleted once the lease expired.
+func (r *Registry) Registered(ctx context.Context, service *registry.ServiceInstance) (bool, error) {
+	key := fmt.Sprintf("%s/%s/%s", r.opts.namespace, service.Name, service.ID)
+	resp, err := r.kv.Get(ctx, key, clientv3.WithCountOnly())
+	if err != nil {
+		return false, err
+	}
+	return resp.Count > 0, nil
+}
+
 // GetService return the service instances in memory according to the service name.
 func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
 	key := fmt.Sprintf("%s/%s", r.opts.namespace, name)
@@ -113,3 +113,18 @@ func (mc *mergeCtx) Value(key interface{}) interface{} {
 	}
 	return mc.parent2.Value(key)
 }
+
+type detachedCtx struct {
+	parent context.Context
+}
+
+// Detach returns a context which carries the values of parent,
+// but is never canceled and has no deadline.
+func Detach(parent context.Context) context.Context {
+	return detachedCtx{parent: parent}
+}
+
+func (detachedCtx) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedCtx) Done() <-chan struct{}               { return nil }
+func (detachedCtx) Err() error                          { return nil }
+func (c detachedCtx) Value(key interface{}) interface{} { return c.parent.Value(key) }
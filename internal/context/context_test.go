@@ -278,3 +278,20 @@ func Test_Err2(t *testing.T) {
 		t.Errorf("expect %v, got %v", context.Canceled, ctx.Err())
 	}
 }
+
+func TestDetach(t *testing.T) {
+	type ctxKey struct{}
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "v"), time.Millisecond)
+	defer cancel()
+	ctx := Detach(parent)
+	<-parent.Done()
+	if ctx.Err() != nil {
+		t.Errorf("expect %v, got %v", nil, ctx.Err())
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("expect no deadline")
+	}
+	if v := ctx.Value(ctxKey{}); v != "v" {
+		t.Errorf("expect %v, got %v", "v", v)
+	}
+}
@@ -0,0 +1,83 @@
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// Header is the header which carries the remaining time of the client deadline,
+// the value is in the grpc-timeout format, e.g. "100m" for 100 milliseconds.
+const Header = "Kratos-Timeout"
+
+// ErrExceeded is the error of a request whose client deadline has been exceeded.
+var ErrExceeded = errors.GatewayTimeout("DEADLINE_EXCEEDED", "the client deadline has been exceeded")
+
+var units = []struct {
+	unit byte
+	d    time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// the max digits of the grpc-timeout format.
+const maxValue = 99999999
+
+// Encode encodes the timeout with the smallest unit of at most 8 digits,
+// it's rounded up so that the timeout is never shortened.
+func Encode(t time.Duration) string {
+	if t <= 0 {
+		return "0n"
+	}
+	for _, u := range units {
+		if v := (t + u.d - 1) / u.d; v <= maxValue {
+			return strconv.FormatInt(int64(v), 10) + string(u.unit)
+		}
+	}
+	return strconv.FormatInt(maxValue, 10) + "H"
+}
+
+// Decode decodes the timeout in the grpc-timeout format.
+func Decode(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("deadline: invalid timeout %q", s)
+	}
+	v, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || v < 0 || v > maxValue {
+		return 0, fmt.Errorf("deadline: invalid timeout %q", s)
+	}
+	for _, u := range units {
+		if u.unit == s[len(s)-1] {
+			if v > int64(math.MaxInt64/u.d) {
+				return math.MaxInt64, nil
+			}
+			return time.Duration(v) * u.d, nil
+		}
+	}
+	return 0, fmt.Errorf("deadline: invalid timeout unit %q", s)
+}
+
+// Check returns a GatewayTimeout error if the remaining time
+// of the ctx deadline is less than the min, it's disabled if the min is zero.
+func Check(ctx context.Context, min time.Duration) error {
+	if min <= 0 {
+		return nil
+	}
+	d, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	if remaining := time.Until(d); remaining < min {
+		return errors.GatewayTimeout("DEADLINE_TOO_SHORT", fmt.Sprintf("remaining deadline %v is less than %v", remaining, min))
+	}
+	return nil
+}
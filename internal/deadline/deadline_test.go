@@ -0,0 +1,56 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		expect string
+	}{
+		{0, "0n"},
+		{100 * time.Nanosecond, "100n"},
+		{time.Second, "1000000u"},
+		{200 * time.Second, "200000m"},
+		{1000 * time.Hour, "3600000S"},
+		{100000 * time.Hour, "6000000M"},
+	}
+	for _, test := range tests {
+		if v := Encode(test.d); v != test.expect {
+			t.Errorf("expect %v, got %v", test.expect, v)
+		}
+		if d, err := Decode(test.expect); err != nil || d != test.d {
+			t.Errorf("expect %v, got %v %v", test.d, d, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, v := range []string{"", "1", "m", "-1m", "1x", "123456789m"} {
+		if _, err := Decode(v); err == nil {
+			t.Errorf("expect error of %q", v)
+		}
+	}
+	if d, err := Decode("99999999H"); err != nil || d <= 0 {
+		t.Errorf("expect a positive timeout, got %v %v", d, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(context.Background(), time.Second); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Check(ctx, 0); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+	if err := Check(ctx, time.Second); !errors.IsGatewayTimeout(err) {
+		t.Errorf("expect gateway timeout, got %v", err)
+	}
+}
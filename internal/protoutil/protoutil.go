@@ -0,0 +1,47 @@
+package protoutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Key returns the operation with the hash of the deterministic proto encoding of the request,
+// it returns an empty key if the request is not a proto message.
+func Key(operation string, req interface{}) string {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return operation + ":" + hex.EncodeToString(sum[:])
+}
+
+// MarshalAny encodes the proto message with its type, so it can be decoded without knowing the type.
+func MarshalAny(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto message", v)
+	}
+	a := new(anypb.Any)
+	if err := anypb.MarshalFrom(a, m, proto.MarshalOptions{Deterministic: true}); err != nil {
+		return nil, err
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(a)
+}
+
+// UnmarshalAny decodes the proto message encoded by MarshalAny.
+func UnmarshalAny(data []byte) (interface{}, error) {
+	var a anypb.Any
+	if err := proto.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	return a.UnmarshalNew()
+}
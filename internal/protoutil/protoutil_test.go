@@ -0,0 +1,41 @@
+package protoutil
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestKey(t *testing.T) {
+	a, b := Key("op", wrapperspb.String("a")), Key("op", wrapperspb.String("a"))
+	if a == "" || a != b {
+		t.Errorf("expect the same key, got %s and %s", a, b)
+	}
+	if c := Key("op", wrapperspb.String("b")); c == a {
+		t.Errorf("expect a different key, got %s", c)
+	}
+	if d := Key("op2", wrapperspb.String("a")); d == a {
+		t.Errorf("expect a different key, got %s", d)
+	}
+	if k := Key("op", "a"); k != "" {
+		t.Errorf("expect an empty key, got %s", k)
+	}
+}
+
+func TestMarshalAny(t *testing.T) {
+	data, err := MarshalAny(wrapperspb.String("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := UnmarshalAny(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(v.(proto.Message), wrapperspb.String("a")) {
+		t.Errorf("expect %v, got %v", wrapperspb.String("a"), v)
+	}
+	if _, err = MarshalAny("a"); err == nil {
+		t.Error("expect error of a non proto message")
+	}
+}
@@ -0,0 +1,56 @@
+package reply
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type isolatedKey struct{}
+
+// NewIsolatedContext returns a new Context which asks the client transport
+// to decode the reply into a new value instead of the caller's one.
+// It's used by middleware which invokes the handler concurrently.
+func NewIsolatedContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, isolatedKey{}, true)
+}
+
+// Isolated reports whether ctx asks for an isolated reply.
+func Isolated(ctx context.Context) bool {
+	v, _ := ctx.Value(isolatedKey{}).(bool)
+	return v
+}
+
+// New returns a new zero value of the type pointed to by v,
+// v is returned if it's not a non-nil pointer.
+func New(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return v
+	}
+	return reflect.New(rv.Type().Elem()).Interface()
+}
+
+// Copy copies the value pointed to by src into dst, nothing is copied if dst or src is nil.
+// It returns an error if they are not pointers of the same type.
+func Copy(dst, src interface{}) error {
+	if dst == nil || src == nil {
+		return nil
+	}
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || sv.Kind() != reflect.Ptr || sv.IsNil() || dv.Type() != sv.Type() {
+		return fmt.Errorf("reply: cannot copy %T into %T", src, dst)
+	}
+	if dv.Pointer() == sv.Pointer() {
+		return nil
+	}
+	if dm, ok := dst.(proto.Message); ok {
+		proto.Reset(dm)
+		proto.Merge(dm, src.(proto.Message))
+		return nil
+	}
+	dv.Elem().Set(sv.Elem())
+	return nil
+}
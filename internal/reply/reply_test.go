@@ -0,0 +1,46 @@
+package reply
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestIsolated(t *testing.T) {
+	if Isolated(context.Background()) {
+		t.Error("expected not isolated")
+	}
+	if !Isolated(NewIsolatedContext(context.Background())) {
+		t.Error("expected isolated")
+	}
+}
+
+type testReply struct {
+	Data string
+}
+
+func TestNewAndCopy(t *testing.T) {
+	dst := &testReply{Data: "old"}
+	src, ok := New(dst).(*testReply)
+	if !ok || src == dst || src.Data != "" {
+		t.Fatalf("unexpected new value %v", src)
+	}
+	src.Data = "new"
+	if err := Copy(dst, src); err != nil || dst.Data != "new" {
+		t.Errorf("expected %s got %s", "new", dst.Data)
+	}
+	if err := Copy(dst, wrapperspb.String("")); err == nil {
+		t.Error("expected copy of different types to fail")
+	}
+	if New(nil) != nil {
+		t.Error("expected nil")
+	}
+}
+
+func TestCopyProto(t *testing.T) {
+	dst := wrapperspb.String("old")
+	if err := Copy(dst, wrapperspb.String("new")); err != nil || dst.Value != "new" {
+		t.Errorf("expected %s got %s", "new", dst.Value)
+	}
+}
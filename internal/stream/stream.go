@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	icontext "github.com/go-kratos/kratos/v2/internal/context"
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+var (
+	// ErrOpened is returned to the attempts after the stream is opened, e.g. by retries or hedging.
+	ErrOpened = errors.Conflict("STREAM_OPENED", "the stream is opened by another attempt")
+	// ErrNotOpened is returned if the middleware returns without opening the stream.
+	ErrNotOpened = errors.InternalServer("STREAM_NOT_OPENED", "the stream is not opened by the middleware")
+)
+
+// OpenFunc opens a stream, the done channel receives the result of the stream once it ends,
+// which is nil if the stream ends normally.
+type OpenFunc func(ctx context.Context) (stream interface{}, done <-chan error, err error)
+
+// Open runs the middleware for the whole lifetime of the stream, so that the middleware,
+// such as tracing and metrics, observe the end of the stream. The stream is opened at most once,
+// with a ctx which carries the values of the middleware, but is only canceled by the ctx of the caller,
+// so the ctx canceled by the middleware on return, e.g. by retries or hedging, never ends the stream.
+func Open(ctx context.Context, m middleware.Middleware, req interface{}, open OpenFunc) (interface{}, error) {
+	if m == nil {
+		s, _, err := open(ctx)
+		return s, err
+	}
+	o := &opener{
+		ctx:    ctx,
+		open:   open,
+		ready:  make(chan struct{}),
+		failed: make(chan error, 1),
+	}
+	go func() {
+		_, err := m(o.handle)(ctx, req)
+		o.returned(err)
+	}()
+	select {
+	case <-o.ready:
+		return o.stream, nil
+	case err := <-o.failed:
+		return nil, err
+	case <-ctx.Done():
+		// the stream opened later is ended by the ctx as well.
+		return nil, ctx.Err()
+	}
+}
+
+type opener struct {
+	ctx    context.Context
+	open   OpenFunc
+	ready  chan struct{}
+	failed chan error
+
+	mu     sync.Mutex
+	stream interface{}
+	opened bool
+}
+
+func (o *opener) handle(ctx context.Context, _ interface{}) (interface{}, error) {
+	o.mu.Lock()
+	if o.opened {
+		o.mu.Unlock()
+		return nil, ErrOpened
+	}
+	sctx, cancel := icontext.Merge(icontext.Detach(ctx), o.ctx)
+	defer cancel()
+	s, done, err := o.open(sctx)
+	if err != nil {
+		o.mu.Unlock()
+		return nil, err
+	}
+	o.stream = s
+	o.opened = true
+	close(o.ready)
+	o.mu.Unlock()
+
+	select {
+	case err = <-done:
+	case <-sctx.Done():
+		err = sctx.Err()
+	}
+	return nil, err
+}
+
+func (o *opener) returned(err error) {
+	o.mu.Lock()
+	opened := o.opened
+	o.mu.Unlock()
+	if opened {
+		return
+	}
+	if err == nil {
+		err = ErrNotOpened
+	}
+	o.failed <- err
+}
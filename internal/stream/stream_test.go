@@ -0,0 +1,42 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+func TestOpen_Cancel(t *testing.T) {
+	ended := make(chan error, 1)
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, err := handler(ctx, req)
+			ended <- err
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var sctx context.Context
+	s, err := Open(ctx, m, nil, func(ctx context.Context) (interface{}, <-chan error, error) {
+		sctx = ctx
+		return "stream", make(chan error), nil
+	})
+	if err != nil || s != "stream" {
+		t.Fatalf("expect %v, got %v %v", "stream", s, err)
+	}
+	// the stream is ended by the ctx of the caller.
+	cancel()
+	select {
+	case err = <-ended:
+		if err != context.Canceled {
+			t.Errorf("expect %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect the middleware to return")
+	}
+	if sctx.Err() == nil {
+		t.Error("expect the stream ctx canceled")
+	}
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/internal/protoutil"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+)
+
+const (
+	resultHit  = "hit"
+	resultMiss = "miss"
+)
+
+// KeyFunc returns the cache key of a request, a request with an empty key is not cached.
+type KeyFunc func(ctx context.Context, req interface{}) string
+
+// Option is cache option.
+type Option func(*options)
+
+// WithTTL with the ttl of the cached replies.
+func WithTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.ttl = d
+	}
+}
+
+// WithStore with the store, the default is an in-memory store of 1024 entries.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithKeyFunc with the key func, the default key is the operation
+// with the hash of the deterministic proto encoding of the request.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(o *options) {
+		o.key = fn
+	}
+}
+
+// WithScope with the func which returns the scope of the request, e.g. the user or tenant id,
+// the replies are cached per scope, so they are never served across the scopes.
+// Without a scope, the replies are shared by all the callers of the same request.
+func WithScope(fn func(ctx context.Context) string) Option {
+	return func(o *options) {
+		o.scope = fn
+	}
+}
+
+// WithCacheControl with the HTTP Cache-Control reply header,
+// the default is "private, max-age=" with the ttl in seconds,
+// as the replies may depend on the caller and must not be stored by the shared caches.
+func WithCacheControl(v string) Option {
+	return func(o *options) {
+		o.cacheControl = v
+	}
+}
+
+// WithRequestCacheControl with honoring the Cache-Control request header, the requests with
+// no-cache skip the lookup, and the ones with no-store are not cached at all.
+// The default is false, as any caller could send every request to the handler with them.
+func WithRequestCacheControl(b bool) Option {
+	return func(o *options) {
+		o.requestCacheControl = b
+	}
+}
+
+// WithRequests with the requests counter, which is labeled by operation and result (hit or miss).
+func WithRequests(c metrics.Counter) Option {
+	return func(o *options) {
+		o.requests = c
+	}
+}
+
+type options struct {
+	ttl          time.Duration
+	store        Store
+	key          KeyFunc
+	scope        func(ctx context.Context) string
+	cacheControl string
+	// honor the Cache-Control request header
+	requestCacheControl bool
+	// counter: server_cache_requests_total{operation, result}
+	requests metrics.Counter
+}
+
+// Server is a server middleware which caches the proto replies by the request key.
+// The key has no scope by default, so a reply is served to every caller of the same request,
+// the operations with caller-specific replies must be cached with WithScope.
+// For the HTTP GET and HEAD requests, the ETag and Cache-Control reply headers are set,
+// and the HTTP server replies 304 Not Modified if the If-None-Match matches the ETag.
+// The operations to cache are chosen by selector.Server(cache.Server()).Path(...).Build().
+func Server(opts ...Option) middleware.Middleware {
+	o := &options{
+		ttl: time.Minute,
+		key: func(ctx context.Context, req interface{}) string {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return ""
+			}
+			return protoutil.Key(tr.Operation(), req)
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore(1024)
+	}
+	if o.cacheControl == "" {
+		o.cacheControl = fmt.Sprintf("private, max-age=%d", int64(o.ttl/time.Second))
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			key := o.key(ctx, req)
+			if key == "" {
+				return handler(ctx, req)
+			}
+			if o.scope != nil {
+				key = o.scope(ctx) + "/" + key
+			}
+			var (
+				operation string
+				ht        khttp.Transporter
+			)
+			noCache, noStore := false, false
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				operation = tr.Operation()
+				if o.requestCacheControl {
+					cc := strings.ToLower(tr.RequestHeader().Get("Cache-Control"))
+					noCache = strings.Contains(cc, "no-cache")
+					noStore = strings.Contains(cc, "no-store")
+				}
+				if t, ok := tr.(khttp.Transporter); ok && t.Request() != nil &&
+					(t.Request().Method == http.MethodGet || t.Request().Method == http.MethodHead) {
+					ht = t
+				}
+			}
+			if noStore {
+				return handler(ctx, req)
+			}
+			if !noCache {
+				data, ok, err := o.store.Get(ctx, key)
+				if err != nil {
+					log.Errorf("[cache] failed to get %s: %v", key, err)
+				}
+				if ok {
+					if reply, err := protoutil.UnmarshalAny(data); err == nil {
+						o.count(operation, resultHit)
+						return o.reply(ht, data, reply)
+					}
+				}
+			}
+			o.count(operation, resultMiss)
+			reply, err := handler(ctx, req)
+			if err != nil {
+				return reply, err
+			}
+			data, err := protoutil.MarshalAny(reply)
+			if err != nil {
+				// not a proto reply
+				return reply, nil
+			}
+			if err = o.store.Set(ctx, key, data, o.ttl); err != nil {
+				log.Errorf("[cache] failed to set %s: %v", key, err)
+			}
+			return o.reply(ht, data, reply)
+		}
+	}
+}
+
+func (o *options) count(operation, result string) {
+	if o.requests != nil {
+		o.requests.With(operation, result).Inc()
+	}
+}
+
+// reply sets the HTTP cache headers.
+func (o *options) reply(ht khttp.Transporter, data []byte, reply interface{}) (interface{}, error) {
+	if ht == nil {
+		return reply, nil
+	}
+	sum := sha256.Sum256(data)
+	ht.ReplyHeader().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	ht.ReplyHeader().Set("Cache-Control", o.cacheControl)
+	return reply, nil
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type Transport struct {
+	kind      transport.Kind
+	operation string
+	request   *http.Request
+	reqHeader headerCarrier
+	repHeader headerCarrier
+}
+
+func (tr *Transport) Kind() transport.Kind            { return tr.kind }
+func (tr *Transport) Endpoint() string                { return "" }
+func (tr *Transport) Operation() string               { return tr.operation }
+func (tr *Transport) RequestHeader() transport.Header { return tr.reqHeader }
+func (tr *Transport) ReplyHeader() transport.Header   { return tr.repHeader }
+func (tr *Transport) Request() *http.Request          { return tr.request }
+func (tr *Transport) PathTemplate() string            { return "" }
+
+type mockCounter struct {
+	lvs    []string
+	counts map[string]int
+}
+
+func (c *mockCounter) With(lvs ...string) metrics.Counter {
+	return &mockCounter{lvs: lvs, counts: c.counts}
+}
+
+func (c *mockCounter) Inc() {
+	c.counts[c.lvs[1]]++
+}
+
+func (c *mockCounter) Add(delta float64) {}
+
+func newContext(method string, header http.Header) (context.Context, *Transport) {
+	tr := &Transport{
+		kind:      transport.KindHTTP,
+		operation: "/test.Service/Get",
+		request:   &http.Request{Method: method},
+		reqHeader: headerCarrier(header),
+		repHeader: headerCarrier(http.Header{}),
+	}
+	return transport.NewServerContext(context.Background(), tr), tr
+}
+
+func TestServer(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("reply " + req.(*wrapperspb.StringValue).Value), nil
+	}
+	c := &mockCounter{counts: make(map[string]int)}
+	h := Server(WithRequests(c))(next)
+
+	ctx, tr := newContext(http.MethodGet, http.Header{})
+	for i := 0; i < 3; i++ {
+		reply, err := h(ctx, wrapperspb.String("a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := reply.(*wrapperspb.StringValue).Value; v != "reply a" {
+			t.Errorf("expect %v, got %v", "reply a", v)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expect %v, got %v", 1, calls)
+	}
+	if c.counts[resultHit] != 2 || c.counts[resultMiss] != 1 {
+		t.Errorf("expect %v hits and %v misses, got %v", 2, 1, c.counts)
+	}
+	if tr.repHeader.Get("ETag") == "" {
+		t.Errorf("expect ETag")
+	}
+	if v := tr.repHeader.Get("Cache-Control"); v != "private, max-age=60" {
+		t.Errorf("expect %v, got %v", "private, max-age=60", v)
+	}
+
+	// the reply is written by the transport, which replies 304 if the ETag matches
+	ctx, _ = newContext(http.MethodGet, http.Header{"If-None-Match": []string{tr.repHeader.Get("ETag")}})
+	reply, err := h(ctx, wrapperspb.String("a"))
+	if err != nil || reply.(*wrapperspb.StringValue).Value != "reply a" {
+		t.Errorf("expect %v, got %v %v", "reply a", reply, err)
+	}
+
+	// the Cache-Control request header is ignored by default
+	ctx, _ = newContext(http.MethodGet, http.Header{"Cache-Control": []string{"no-cache"}})
+	if _, err = h(ctx, wrapperspb.String("a")); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("expect %v, got %v", 1, calls)
+	}
+}
+
+func TestServer_RequestCacheControl(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("reply " + req.(*wrapperspb.StringValue).Value), nil
+	}
+	h := Server(WithRequestCacheControl(true))(next)
+
+	// no-store is not cached at all
+	ctx, _ := newContext(http.MethodGet, http.Header{"Cache-Control": []string{"no-store"}})
+	if _, err := h(ctx, wrapperspb.String("a")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ = newContext(http.MethodGet, http.Header{})
+	if _, err := h(ctx, wrapperspb.String("a")); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expect %v, got %v", 2, calls)
+	}
+	// no-cache skips the lookup
+	ctx, _ = newContext(http.MethodGet, http.Header{"Cache-Control": []string{"no-cache"}})
+	if _, err := h(ctx, wrapperspb.String("a")); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expect %v, got %v", 3, calls)
+	}
+}
+
+func TestServer_NotCached(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if req == "error" {
+			return nil, errors.InternalServer("", "")
+		}
+		return req, nil
+	}
+	h := Server(WithKeyFunc(func(_ context.Context, req interface{}) string { return req.(string) }))(next)
+	for i := 0; i < 2; i++ {
+		// not a proto reply
+		_, _ = h(context.Background(), "req")
+		_, _ = h(context.Background(), "error")
+	}
+	if calls != 4 {
+		t.Errorf("expect %v, got %v", 4, calls)
+	}
+}
+
+func TestServer_Scope(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return req, nil
+	}
+	type userKey struct{}
+	h := Server(WithScope(func(ctx context.Context) string {
+		user, _ := ctx.Value(userKey{}).(string)
+		return user
+	}))(next)
+	for _, user := range []string{"alice", "bob", "alice"} {
+		ctx, _ := newContext(http.MethodGet, http.Header{})
+		ctx = context.WithValue(ctx, userKey{}, user)
+		if _, err := h(ctx, wrapperspb.String("a")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expect %v, got %v", 2, calls)
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store is the store of the cached replies.
+type Store interface {
+	// Get returns the value of the key, ok is false if it's missing or expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set sets the value of the key with the ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type entry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+// MemoryStore is an in-memory store with the max size and LRU eviction.
+type MemoryStore struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+// NewMemoryStore creates an in-memory store which keeps at most size entries.
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// Get returns the value of the key.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	ent := e.Value.(*entry)
+	if !s.now().Before(ent.expireAt) {
+		s.remove(e)
+		return nil, false, nil
+	}
+	s.ll.MoveToFront(e)
+	return ent.value, true, nil
+}
+
+// Set sets the value of the key, the least recently used entry is evicted if it's full.
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expireAt := s.now().Add(ttl)
+	if e, ok := s.items[key]; ok {
+		ent := e.Value.(*entry)
+		ent.value = value
+		ent.expireAt = expireAt
+		s.ll.MoveToFront(e)
+		return nil
+	}
+	s.items[key] = s.ll.PushFront(&entry{key: key, value: value, expireAt: expireAt})
+	for s.size > 0 && s.ll.Len() > s.size {
+		s.remove(s.ll.Back())
+	}
+	return nil
+}
+
+// Len returns the number of entries, including the expired ones not evicted yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+func (s *MemoryStore) remove(e *list.Element) {
+	s.ll.Remove(e)
+	delete(s.items, e.Value.(*entry).key)
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewMemoryStore(2)
+	s.now = func() time.Time { return now }
+
+	_ = s.Set(ctx, "a", []byte("1"), time.Second)
+	_ = s.Set(ctx, "b", []byte("2"), time.Second)
+	// a is recently used
+	if v, ok, _ := s.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Errorf("expect %v, got %v %v", "1", string(v), ok)
+	}
+	_ = s.Set(ctx, "c", []byte("3"), time.Second)
+	if _, ok, _ := s.Get(ctx, "b"); ok {
+		t.Errorf("expect b to be evicted")
+	}
+	if s.Len() != 2 {
+		t.Errorf("expect %v, got %v", 2, s.Len())
+	}
+
+	now = now.Add(time.Second)
+	if _, ok, _ := s.Get(ctx, "a"); ok {
+		t.Errorf("expect a to be expired")
+	}
+	if s.Len() != 1 {
+		t.Errorf("expect %v, got %v", 1, s.Len())
+	}
+}
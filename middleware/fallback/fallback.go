@@ -0,0 +1,173 @@
+package fallback
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/protoutil"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/cache"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// Func returns a degraded reply for the request which failed with the error.
+type Func func(ctx context.Context, req interface{}, err error) (interface{}, error)
+
+// Option is fallback option.
+type Option func(*options)
+
+// WithFallback with the fallback of the operation.
+func WithFallback(operation string, fn Func) Option {
+	return func(o *options) {
+		o.fallbacks[operation] = fn
+	}
+}
+
+// WithDefault with the fallback of the operations without their own one.
+func WithDefault(fn Func) Option {
+	return func(o *options) {
+		o.def = fn
+	}
+}
+
+// WithErrorHandler with the func which reports whether the error triggers the fallback.
+func WithErrorHandler(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.isFallback = fn
+	}
+}
+
+// WithStaleCache with the stale cache, the last good reply of the same request key
+// is kept within the ttl, and served before the fallback funcs when the request fails.
+// The default store is an in-memory store of 1024 entries, and the request key is the operation with the hash of the request.
+func WithStaleCache(s cache.Store, ttl time.Duration) Option {
+	return func(o *options) {
+		if s == nil {
+			s = cache.NewMemoryStore(1024)
+		}
+		o.store = s
+		o.ttl = ttl
+	}
+}
+
+// WithScope with the func which returns the scope of the request, e.g. the user or tenant id,
+// the stale replies are kept per scope, so they are never served across the scopes.
+func WithScope(fn func(ctx context.Context) string) Option {
+	return func(o *options) {
+		o.scope = fn
+	}
+}
+
+type options struct {
+	fallbacks  map[string]Func
+	def        Func
+	isFallback func(err error) bool
+	store      cache.Store
+	ttl        time.Duration
+	scope      func(ctx context.Context) string
+}
+
+// isFallback is the default error handler, which triggers the fallback when the request
+// is rejected by the rate limiter, the circuit breaker, or it's timed out.
+func isFallback(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch errors.FromError(err).Code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Server is a server fallback middleware.
+func Server(opts ...Option) middleware.Middleware {
+	return newMiddleware(func(ctx context.Context) (transport.Transporter, bool) {
+		return transport.FromServerContext(ctx)
+	}, opts...)
+}
+
+// Client is a client fallback middleware, it's usually placed before
+// the circuit breaker and the rate limiter to degrade their rejections.
+func Client(opts ...Option) middleware.Middleware {
+	return newMiddleware(func(ctx context.Context) (transport.Transporter, bool) {
+		return transport.FromClientContext(ctx)
+	}, opts...)
+}
+
+func newMiddleware(fromContext func(context.Context) (transport.Transporter, bool), opts ...Option) middleware.Middleware {
+	o := &options{
+		fallbacks:  make(map[string]Func),
+		isFallback: isFallback,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var operation string
+			if tr, ok := fromContext(ctx); ok {
+				operation = tr.Operation()
+			}
+			var key string
+			if o.store != nil {
+				key = protoutil.Key(operation, req)
+				if key != "" && o.scope != nil {
+					key = o.scope(ctx) + "/" + key
+				}
+			}
+			reply, err := handler(ctx, req)
+			if err == nil {
+				if key != "" {
+					o.save(ctx, key, reply)
+				}
+				return reply, nil
+			}
+			if !o.isFallback(err) {
+				return reply, err
+			}
+			if key != "" {
+				if stale, ok := o.load(ctx, key); ok {
+					return stale, nil
+				}
+			}
+			fn, ok := o.fallbacks[operation]
+			if !ok {
+				fn = o.def
+			}
+			if fn == nil {
+				return reply, err
+			}
+			return fn(ctx, req, err)
+		}
+	}
+}
+
+func (o *options) save(ctx context.Context, key string, reply interface{}) {
+	data, err := protoutil.MarshalAny(reply)
+	if err != nil {
+		// not a proto reply
+		return
+	}
+	if err = o.store.Set(ctx, key, data, o.ttl); err != nil {
+		log.Errorf("[fallback] failed to set %s: %v", key, err)
+	}
+}
+
+func (o *options) load(ctx context.Context, key string) (interface{}, bool) {
+	data, ok, err := o.store.Get(ctx, key)
+	if err != nil {
+		log.Errorf("[fallback] failed to get %s: %v", key, err)
+	}
+	if !ok {
+		return nil, false
+	}
+	reply, err := protoutil.UnmarshalAny(data)
+	if err != nil {
+		return nil, false
+	}
+	return reply, true
+}
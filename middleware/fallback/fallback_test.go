@@ -0,0 +1,133 @@
+package fallback
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type Transport struct {
+	transport.Transporter
+	operation string
+}
+
+func (tr *Transport) Operation() string { return tr.operation }
+
+func newContext(operation string) context.Context {
+	return transport.NewServerContext(context.Background(), &Transport{operation: operation})
+}
+
+func TestServer(t *testing.T) {
+	unavailable := errors.ServiceUnavailable("UNAVAILABLE", "")
+	badRequest := errors.BadRequest("BAD_REQUEST", "")
+	h := Server(
+		WithFallback("/test.Service/Get", func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+			return "get", nil
+		}),
+		WithDefault(func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+			return "default", nil
+		}),
+	)
+	tests := []struct {
+		operation string
+		err       error
+		reply     interface{}
+		expectErr error
+	}{
+		{"/test.Service/Get", nil, "ok", nil},
+		{"/test.Service/Get", unavailable, "get", nil},
+		{"/test.Service/List", unavailable, "default", nil},
+		{"/test.Service/List", context.DeadlineExceeded, "default", nil},
+		{"/test.Service/Get", badRequest, nil, badRequest},
+	}
+	for _, test := range tests {
+		reply, err := h(func(ctx context.Context, req interface{}) (interface{}, error) {
+			if test.err != nil {
+				return nil, test.err
+			}
+			return "ok", nil
+		})(newContext(test.operation), "req")
+		if err != test.expectErr {
+			t.Errorf("expect %v, got %v", test.expectErr, err)
+		}
+		if reply != test.reply {
+			t.Errorf("expect %v, got %v", test.reply, reply)
+		}
+	}
+}
+
+func TestStaleCache(t *testing.T) {
+	var fail bool
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if fail {
+			return nil, errors.ServiceUnavailable("UNAVAILABLE", "")
+		}
+		return wrapperspb.String("hello " + req.(*wrapperspb.StringValue).Value), nil
+	}
+	h := Server(WithStaleCache(nil, time.Minute))(next)
+	ctx := newContext("/test.Service/Get")
+	if _, err := h(ctx, wrapperspb.String("kratos")); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	reply, err := h(ctx, wrapperspb.String("kratos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(reply.(proto.Message), wrapperspb.String("hello kratos")) {
+		t.Errorf("expect %v, got %v", "hello kratos", reply)
+	}
+	// no stale reply of another request
+	if _, err = h(ctx, wrapperspb.String("go")); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expect service unavailable, got %v", err)
+	}
+}
+
+func TestStaleCache_Scope(t *testing.T) {
+	var fail bool
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if fail {
+			return nil, errors.ServiceUnavailable("UNAVAILABLE", "")
+		}
+		return wrapperspb.String("hello"), nil
+	}
+	type userKey struct{}
+	h := Server(WithStaleCache(nil, time.Minute), WithScope(func(ctx context.Context) string {
+		user, _ := ctx.Value(userKey{}).(string)
+		return user
+	}))(next)
+	alice := context.WithValue(newContext("/test.Service/Get"), userKey{}, "alice")
+	bob := context.WithValue(newContext("/test.Service/Get"), userKey{}, "bob")
+	if _, err := h(alice, wrapperspb.String("kratos")); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if _, err := h(alice, wrapperspb.String("kratos")); err != nil {
+		t.Errorf("expect the stale reply, got %v", err)
+	}
+	if _, err := h(bob, wrapperspb.String("kratos")); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expect no stale reply of another scope, got %v", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	h := Client(
+		WithErrorHandler(func(err error) bool { return errors.IsNotFound(err) }),
+		WithDefault(func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+			return "default", nil
+		}),
+	)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.NotFound("NOT_FOUND", "")
+	})
+	ctx := transport.NewClientContext(context.Background(), &Transport{operation: "/test.Service/Get"})
+	reply, err := h(ctx, "req")
+	if err != nil || reply != "default" {
+		t.Errorf("expect %v, got %v %v", "default", reply, err)
+	}
+}
@@ -0,0 +1,220 @@
+package hedging
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	ireply "github.com/go-kratos/kratos/v2/internal/reply"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+const (
+	// the number of latency samples kept per operation.
+	sampleSize = 128
+	// the minimum number of samples to derive the delay from.
+	minSamples = 16
+)
+
+// Option is hedging option.
+type Option func(*options)
+
+// WithDelay with the delay before sending a hedged request,
+// it's also used until enough latency samples are collected when a percentile is set.
+func WithDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.delay = d
+	}
+}
+
+// WithPercentile with the latency percentile which the delay is derived from, e.g. 0.95.
+func WithPercentile(p float64) Option {
+	return func(o *options) {
+		o.percentile = p
+	}
+}
+
+// WithMaxAttempts with max attempts, including the original request.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.attempts = n
+	}
+}
+
+type options struct {
+	delay      time.Duration
+	percentile float64
+	attempts   int
+}
+
+// Client is a client hedging middleware, after the delay a hedged request
+// is sent to a different node, the first success is taken and the others are canceled.
+// Hedging must only be enabled for idempotent operations, e.g. by
+// selector.Client(hedging.Client()).Path("/api.Service/Get").Build().
+// It should be placed after the middleware which writes the request header,
+// such as tracing and metadata, as the attempts share the same transport.
+func Client(opts ...Option) middleware.Middleware {
+	o := &options{
+		delay:    100 * time.Millisecond,
+		attempts: 2,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	l := &latency{samples: make(map[string]*samples)}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var operation string
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				operation = tr.Operation()
+			}
+			delay := o.delay
+			if o.percentile > 0 {
+				if d, ok := l.percentile(operation, o.percentile); ok {
+					delay = d
+				}
+			}
+			start := time.Now()
+			reply, node, err := o.hedge(ctx, handler, req, delay)
+			if err == nil {
+				l.observe(operation, time.Since(start))
+				if p, ok := selector.FromPeerContext(ctx); ok {
+					p.Node = node
+				}
+			}
+			return reply, err
+		}
+	}
+}
+
+type result struct {
+	reply interface{}
+	node  selector.Node
+	err   error
+}
+
+func (o *options) hedge(ctx context.Context, handler middleware.Handler, req interface{}, delay time.Duration) (interface{}, selector.Node, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		picked  = &picked{}
+		results = make(chan result, o.attempts)
+	)
+	launch := func() {
+		go func() {
+			var p selector.Peer
+			actx := selector.NewPeerContext(ireply.NewIsolatedContext(ctx), &p)
+			actx = selector.NewSelectOptionsContext(actx,
+				selector.WithFilter(picked.filter),
+				selector.WithOnPicked(picked.add),
+			)
+			reply, err := handler(actx, req)
+			results <- result{reply: reply, node: p.Node, err: err}
+		}()
+	}
+	launch()
+	launched := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var err error
+	for received := 0; received < launched; {
+		select {
+		case <-timer.C:
+			if launched < o.attempts {
+				launch()
+				launched++
+				timer.Reset(delay)
+			}
+		case r := <-results:
+			received++
+			if r.err == nil {
+				return r.reply, r.node, nil
+			}
+			err = r.err
+		}
+	}
+	return nil, nil, err
+}
+
+// picked records the nodes picked by the attempts,
+// so that a hedged request is sent to a different node.
+type picked struct {
+	mu    sync.Mutex
+	addrs map[string]struct{}
+}
+
+func (p *picked) add(n selector.Node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.addrs == nil {
+		p.addrs = make(map[string]struct{})
+	}
+	p.addrs[n.Address()] = struct{}{}
+}
+
+// filter excludes the picked nodes, unless no other node is left.
+func (p *picked) filter(_ context.Context, nodes []selector.Node) []selector.Node {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.addrs) == 0 {
+		return nodes
+	}
+	newNodes := make([]selector.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if _, ok := p.addrs[n.Address()]; !ok {
+			newNodes = append(newNodes, n)
+		}
+	}
+	if len(newNodes) == 0 {
+		return nodes
+	}
+	return newNodes
+}
+
+// latency keeps the recent latency samples per operation.
+type latency struct {
+	mu      sync.Mutex
+	samples map[string]*samples
+}
+
+type samples struct {
+	values []time.Duration
+	next   int
+}
+
+func (l *latency) observe(operation string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.samples[operation]
+	if !ok {
+		s = &samples{values: make([]time.Duration, 0, sampleSize)}
+		l.samples[operation] = s
+	}
+	if len(s.values) < sampleSize {
+		s.values = append(s.values, d)
+		return
+	}
+	s.values[s.next] = d
+	s.next = (s.next + 1) % sampleSize
+}
+
+func (l *latency) percentile(operation string, p float64) (time.Duration, bool) {
+	l.mu.Lock()
+	s, ok := l.samples[operation]
+	if !ok || len(s.values) < minSamples {
+		l.mu.Unlock()
+		return 0, false
+	}
+	values := make([]time.Duration, len(s.values))
+	copy(values, s.values)
+	l.mu.Unlock()
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	i := int(p * float64(len(values)))
+	if i >= len(values) {
+		i = len(values) - 1
+	}
+	return values[i], true
+}
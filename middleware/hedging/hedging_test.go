@@ -0,0 +1,108 @@
+package hedging
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/random"
+)
+
+func newSelector(addrs ...string) selector.Selector {
+	s := random.New()
+	nodes := make([]selector.Node, 0, len(addrs))
+	for _, addr := range addrs {
+		nodes = append(nodes, selector.NewNode("http", addr, &registry.ServiceInstance{}))
+	}
+	s.Apply(nodes)
+	return s
+}
+
+func TestHedging(t *testing.T) {
+	s := newSelector("slow", "fast")
+	var calls int32
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		n, done, err := s.Select(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer done(ctx, selector.DoneInfo{})
+		// the first attempt is slow on whichever node is picked
+		if atomic.LoadInt32(&calls) == 1 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return n.Address(), nil
+	}
+	var p selector.Peer
+	ctx := selector.NewPeerContext(context.Background(), &p)
+	start := time.Now()
+	reply, err := Client(WithDelay(10*time.Millisecond))(next)(ctx, "req")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) >= time.Second {
+		t.Errorf("expected the hedged request to win")
+	}
+	if p.Node == nil || p.Node.Address() != reply {
+		t.Errorf("expected peer %v, got %v", reply, p.Node)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("expected %d calls, got %d", 2, calls)
+	}
+}
+
+func TestHedgingDifferentNode(t *testing.T) {
+	s := newSelector("a", "b")
+	addrs := make(chan string, 2)
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		n, _, err := s.Select(ctx)
+		if err != nil {
+			return nil, err
+		}
+		addrs <- n.Address()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := Client(WithDelay(time.Millisecond))(next)(ctx, "req")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if a, b := <-addrs, <-addrs; a == b {
+		t.Errorf("expected different nodes, got %s and %s", a, b)
+	}
+}
+
+func TestHedgingError(t *testing.T) {
+	want := errors.New("failed")
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, want
+	}
+	if _, err := Client(WithDelay(time.Hour))(next)(context.Background(), "req"); err != want {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	l := &latency{samples: make(map[string]*samples)}
+	if _, ok := l.percentile("op", 0.9); ok {
+		t.Fatal("expected no percentile without samples")
+	}
+	for i := 1; i <= sampleSize*2; i++ {
+		l.observe("op", time.Duration(i%100)*time.Millisecond)
+	}
+	d, ok := l.percentile("op", 0.9)
+	if !ok || d < 80*time.Millisecond || d > 99*time.Millisecond {
+		t.Errorf("unexpected percentile %s", d)
+	}
+}
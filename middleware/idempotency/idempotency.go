@@ -0,0 +1,264 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/proto"
+	"github.com/go-kratos/kratos/v2/errors"
+	icontext "github.com/go-kratos/kratos/v2/internal/context"
+	"github.com/go-kratos/kratos/v2/internal/protoutil"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// DefaultHeader is the default header of the idempotency key.
+const DefaultHeader = "Idempotency-Key"
+
+// the min interval of polling the request in progress
+const minPollInterval = 10 * time.Millisecond
+
+var (
+	// ErrConflict is returned when a request with the same idempotency key is in progress.
+	ErrConflict = errors.Conflict("IDEMPOTENCY_KEY_IN_USE", "a request with the same idempotency key is in progress")
+	// ErrMismatch is returned when the idempotency key is reused with a different request.
+	ErrMismatch = errors.New(http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_MISMATCH", "the idempotency key is reused with a different request")
+)
+
+// Option is idempotency option.
+type Option func(*options)
+
+// WithHeader with the header of the idempotency key.
+func WithHeader(name string) Option {
+	return func(o *options) {
+		o.header = name
+	}
+}
+
+// WithStore with the store, the default is an in-memory store.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithTTL with the ttl of the finished records.
+func WithTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.ttl = d
+	}
+}
+
+// WithLockTTL with the ttl of the in-progress records, which should be longer than the handling,
+// the key is released after it if the server crashed in the middle of the request.
+func WithLockTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.lockTTL = d
+	}
+}
+
+// WithCodec with the codec which encodes the stored replies and errors.
+func WithCodec(c encoding.Codec) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// WithWait with the max time a duplicate waits for the request in progress,
+// ErrConflict is returned if it's not finished in time, or immediately if d is zero.
+func WithWait(d time.Duration) Option {
+	return func(o *options) {
+		o.wait = d
+	}
+}
+
+type options struct {
+	header  string
+	store   Store
+	ttl     time.Duration
+	lockTTL time.Duration
+	codec   encoding.Codec
+	wait    time.Duration
+}
+
+// Server is a server middleware which replays the first result of the requests
+// with the same idempotency key of the same operation, the key is read from the request header.
+// The replies must be proto messages, the errors with 5xx codes, the panics and the canceled
+// requests are not stored, so that they can be retried.
+// The fingerprint of a proto request is stored, and ErrMismatch is returned if the key
+// is reused with a different request.
+func Server(opts ...Option) middleware.Middleware {
+	o := &options{
+		header:  DefaultHeader,
+		ttl:     24 * time.Hour,
+		lockTTL: time.Minute,
+		codec:   encoding.GetCodec("proto"),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore()
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			id := tr.RequestHeader().Get(o.header)
+			if id == "" {
+				return handler(ctx, req)
+			}
+			key := tr.Operation() + ":" + id
+			fingerprint := protoutil.Key(tr.Operation(), req)
+			rec, started, err := o.store.Start(ctx, key, o.lockTTL)
+			if err != nil {
+				return nil, err
+			}
+			if !started && !rec.Done && o.wait > 0 {
+				if rec, started, err = o.await(ctx, key); err != nil {
+					return nil, err
+				}
+			}
+			if !started {
+				if !rec.Done {
+					return nil, ErrConflict
+				}
+				if rec.Fingerprint != "" && fingerprint != "" && rec.Fingerprint != fingerprint {
+					return nil, ErrMismatch
+				}
+				return o.replay(rec)
+			}
+			return o.handle(ctx, key, fingerprint, handler, req)
+		}
+	}
+}
+
+// handle runs the handler, and stores its result or releases the key,
+// the key is released if the handler panics as well.
+func (o *options) handle(ctx context.Context, key, fingerprint string, handler middleware.Handler, req interface{}) (reply interface{}, err error) {
+	// the store is updated even if the request is canceled
+	sctx := icontext.Detach(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			o.release(sctx, key)
+			panic(r)
+		}
+	}()
+	reply, err = handler(ctx, req)
+	if rec, ok := o.record(ctx, reply, err); ok {
+		rec.Fingerprint = fingerprint
+		if serr := o.store.Finish(sctx, key, rec, o.ttl); serr != nil {
+			log.Errorf("[idempotency] failed to store %s: %v", key, serr)
+		}
+		return reply, err
+	}
+	o.release(sctx, key)
+	return reply, err
+}
+
+// release deletes the record of the key, so that the request can be retried.
+func (o *options) release(ctx context.Context, key string) {
+	if err := o.store.Delete(ctx, key); err != nil {
+		log.Errorf("[idempotency] failed to delete %s: %v", key, err)
+	}
+}
+
+// record returns the record of the result, it reports false if it should not be stored.
+func (o *options) record(ctx context.Context, reply interface{}, err error) (*Record, bool) {
+	if err != nil {
+		se := errors.FromError(err)
+		if se.Code >= 500 || ctx.Err() != nil {
+			return nil, false
+		}
+		data, merr := o.codec.Marshal(&se.Status)
+		if merr != nil {
+			return nil, false
+		}
+		return &Record{Done: true, Error: data}, true
+	}
+	m, ok := reply.(proto.Message)
+	if !ok {
+		return nil, false
+	}
+	data, merr := o.codec.Marshal(m)
+	if merr != nil {
+		return nil, false
+	}
+	return &Record{Done: true, Type: string(m.ProtoReflect().Descriptor().FullName()), Reply: data}, true
+}
+
+// await waits for the request in progress to finish, it reports started
+// if the request in progress failed and this request takes over the key.
+func (o *options) await(ctx context.Context, key string) (*Record, bool, error) {
+	timer := time.NewTimer(o.wait)
+	defer timer.Stop()
+	interval := o.wait / 20
+	if interval < minPollInterval {
+		interval = minPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-timer.C:
+			return nil, false, ErrConflict
+		case <-ticker.C:
+		}
+		rec, started, err := o.store.Start(ctx, key, o.lockTTL)
+		if err != nil || started || rec.Done {
+			return rec, started, err
+		}
+	}
+}
+
+// replay returns the result of the finished record.
+func (o *options) replay(rec *Record) (interface{}, error) {
+	if rec.Error != nil {
+		se := new(errors.Error)
+		if err := o.codec.Unmarshal(rec.Error, &se.Status); err != nil {
+			return nil, err
+		}
+		return nil, se
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(rec.Type))
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: unknown reply type %s: %w", rec.Type, err)
+	}
+	reply := mt.New().Interface()
+	if err := o.codec.Unmarshal(rec.Reply, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Client is a client middleware which sets a new idempotency key to the request header if it's absent,
+// it should be placed before the retry middleware, so that all the attempts share the same key.
+func Client(opts ...Option) middleware.Middleware {
+	o := &options{header: DefaultHeader}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				if tr.RequestHeader().Get(o.header) == "" {
+					tr.RequestHeader().Set(o.header, uuid.NewString())
+				}
+			}
+			return handler(ctx, req)
+		}
+	}
+}
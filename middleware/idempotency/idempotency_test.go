@@ -0,0 +1,235 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type Transport struct {
+	transport.Transporter
+	operation string
+	reqHeader headerCarrier
+}
+
+func (tr *Transport) Operation() string               { return tr.operation }
+func (tr *Transport) RequestHeader() transport.Header { return tr.reqHeader }
+
+func newContext(key string) context.Context {
+	h := http.Header{}
+	if key != "" {
+		h.Set(DefaultHeader, key)
+	}
+	return transport.NewServerContext(context.Background(), &Transport{operation: "/test.Service/Create", reqHeader: headerCarrier(h)})
+}
+
+func TestServer(t *testing.T) {
+	for _, codec := range []string{"proto", "json"} {
+		var calls int32
+		next := func(ctx context.Context, req interface{}) (interface{}, error) {
+			n := atomic.AddInt32(&calls, 1)
+			if req == "bad" {
+				return nil, errors.BadRequest("BAD", "bad request")
+			}
+			return wrapperspb.Int32(n), nil
+		}
+		h := Server(WithCodec(encoding.GetCodec(codec)))(next)
+		for i := 0; i < 3; i++ {
+			reply, err := h(newContext("1"), "req")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := reply.(*wrapperspb.Int32Value).Value; v != 1 {
+				t.Errorf("%s: expect %v, got %v", codec, 1, v)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			_, err := h(newContext("2"), "bad")
+			if !errors.IsBadRequest(err) || errors.Reason(err) != "BAD" {
+				t.Errorf("%s: expect %v, got %v", codec, "BAD", err)
+			}
+		}
+		// without the key
+		_, _ = h(newContext(""), "req")
+		if n := atomic.LoadInt32(&calls); n != 3 {
+			t.Errorf("%s: expect %v, got %v", codec, 3, n)
+		}
+	}
+}
+
+func TestServer_ServerError(t *testing.T) {
+	var calls int32
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, errors.ServiceUnavailable("UNAVAILABLE", "")
+		}
+		return wrapperspb.String("ok"), nil
+	}
+	h := Server()(next)
+	if _, err := h(newContext("1"), "req"); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expect %v, got %v", "UNAVAILABLE", err)
+	}
+	// the 5xx error is not stored, so the retry is executed
+	reply, err := h(newContext("1"), "req")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.(*wrapperspb.StringValue).Value != "ok" {
+		t.Errorf("expect %v, got %v", "ok", reply)
+	}
+}
+
+func TestServer_Concurrent(t *testing.T) {
+	release := make(chan struct{})
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-release
+		return wrapperspb.String("ok"), nil
+	}
+	for _, wait := range []time.Duration{0, time.Second} {
+		release = make(chan struct{})
+		h := Server(WithWait(wait))(next)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = h(newContext("1"), "req")
+		}()
+		time.Sleep(10 * time.Millisecond)
+		if wait == 0 {
+			if _, err := h(newContext("1"), "req"); !errors.IsConflict(err) {
+				t.Errorf("expect %v, got %v", ErrConflict, err)
+			}
+			close(release)
+		} else {
+			time.AfterFunc(20*time.Millisecond, func() { close(release) })
+			reply, err := h(newContext("1"), "req")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reply.(*wrapperspb.StringValue).Value != "ok" {
+				t.Errorf("expect %v, got %v", "ok", reply)
+			}
+		}
+		<-done
+	}
+}
+
+func TestServer_Panic(t *testing.T) {
+	var calls int32
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("boom")
+		}
+		return wrapperspb.String("ok"), nil
+	}
+	h := Server()(next)
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expect the panic to be propagated")
+			}
+		}()
+		_, _ = h(newContext("1"), "req")
+	}()
+	// the key is released, so the retry is executed
+	reply, err := h(newContext("1"), "req")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.(*wrapperspb.StringValue).Value != "ok" {
+		t.Errorf("expect %v, got %v", "ok", reply)
+	}
+}
+
+func TestServer_Mismatch(t *testing.T) {
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return wrapperspb.String("ok"), nil
+	}
+	h := Server()(next)
+	if _, err := h(newContext("1"), wrapperspb.String("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h(newContext("1"), wrapperspb.String("a")); err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	if _, err := h(newContext("1"), wrapperspb.String("b")); errors.Reason(err) != "IDEMPOTENCY_KEY_MISMATCH" {
+		t.Errorf("expect %v, got %v", ErrMismatch, err)
+	}
+}
+
+func TestServer_LockTTL(t *testing.T) {
+	s := NewMemoryStore()
+	h := Server(WithStore(s), WithLockTTL(10*time.Millisecond), WithWait(time.Nanosecond))(
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return wrapperspb.String("ok"), nil
+		})
+	// the in-progress record of a crashed server
+	if _, started, err := s.Start(context.Background(), "/test.Service/Create:1", 10*time.Millisecond); err != nil || !started {
+		t.Fatalf("expect started, got %v %v", started, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := h(newContext("1"), "req"); err != nil {
+		t.Errorf("expect the expired lock to be taken over, got %v", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	h := http.Header{}
+	ctx := transport.NewClientContext(context.Background(), &Transport{reqHeader: headerCarrier(h)})
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	_, _ = Client()(next)(ctx, nil)
+	key := h.Get(DefaultHeader)
+	if key == "" {
+		t.Fatal("expect idempotency key")
+	}
+	// the existing key is kept
+	_, _ = Client()(next)(ctx, nil)
+	if h.Get(DefaultHeader) != key {
+		t.Errorf("expect %v, got %v", key, h.Get(DefaultHeader))
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	if _, started, _ := s.Start(ctx, "a", time.Second); !started {
+		t.Errorf("expect started")
+	}
+	if rec, started, _ := s.Start(ctx, "a", time.Second); started || rec.Done {
+		t.Errorf("expect in progress")
+	}
+	_ = s.Finish(ctx, "a", &Record{Done: true}, time.Second)
+	if rec, _, _ := s.Start(ctx, "a", time.Second); !rec.Done {
+		t.Errorf("expect done")
+	}
+	now = now.Add(2 * time.Second)
+	if _, started, _ := s.Start(ctx, "a", time.Second); !started {
+		t.Errorf("expect started after expired")
+	}
+}
@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Record is the result of a request with an idempotency key.
+type Record struct {
+	// Done reports whether the request is finished, it's in progress otherwise.
+	Done bool
+	// Type is the full name of the proto reply.
+	Type string
+	// Reply is the reply encoded by the codec.
+	Reply []byte
+	// Error is the errors.Status encoded by the codec.
+	Error []byte
+	// Fingerprint is the hash of the request, which is empty if the request is not a proto message.
+	Fingerprint string
+}
+
+// Store is the store of the idempotency records.
+type Store interface {
+	// Start stores an in-progress record of the key if it's absent,
+	// it returns the existing record and false otherwise.
+	Start(ctx context.Context, key string, ttl time.Duration) (rec *Record, started bool, err error)
+	// Finish stores the finished record of the key.
+	Finish(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+	// Delete deletes the record of the key, so that the request can be retried.
+	Delete(ctx context.Context, key string) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type memoryRecord struct {
+	rec      *Record
+	expireAt time.Time
+}
+
+// MemoryStore is an in-memory store.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	sweepAt time.Time
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]memoryRecord),
+		now:     time.Now,
+	}
+}
+
+// Start stores an in-progress record of the key if it's absent.
+func (s *MemoryStore) Start(_ context.Context, key string, ttl time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if r, ok := s.records[key]; ok && now.Before(r.expireAt) {
+		return r.rec, false, nil
+	}
+	// sweep the expired records once a minute
+	if now.After(s.sweepAt) {
+		for k, r := range s.records {
+			if !now.Before(r.expireAt) {
+				delete(s.records, k)
+			}
+		}
+		s.sweepAt = now.Add(time.Minute)
+	}
+	s.records[key] = memoryRecord{rec: &Record{}, expireAt: now.Add(ttl)}
+	return nil, true, nil
+}
+
+// Finish stores the finished record of the key.
+func (s *MemoryStore) Finish(_ context.Context, key string, rec *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryRecord{rec: rec, expireAt: s.now().Add(ttl)}
+	return nil
+}
+
+// Delete deletes the record of the key.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
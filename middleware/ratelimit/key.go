@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/aegis/ratelimit"
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/http"
+
+	"google.golang.org/grpc/peer"
+)
+
+// the idle time after which a keyed limiter is released.
+const idleTimeout = 10 * time.Minute
+
+// KeyFunc returns the key of the limiter for the request,
+// e.g. the operation, the JWT subject or the client IP.
+type KeyFunc func(ctx context.Context) string
+
+// OperationKey keys the limiter by the operation.
+func OperationKey(ctx context.Context) string {
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		return tr.Operation()
+	}
+	if tr, ok := transport.FromClientContext(ctx); ok {
+		return tr.Operation()
+	}
+	return ""
+}
+
+// PeerKey keys the limiter by the client IP.
+func PeerKey(ctx context.Context) string {
+	var addr string
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		if ht, ok := tr.(http.Transporter); ok {
+			addr = ht.Request().RemoteAddr
+		}
+	}
+	if addr == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			addr = p.Addr.String()
+		}
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// HeaderKey keys the limiter by the request header.
+func HeaderKey(name string) KeyFunc {
+	return func(ctx context.Context) string {
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			return tr.RequestHeader().Get(name)
+		}
+		if tr, ok := transport.FromClientContext(ctx); ok {
+			return tr.RequestHeader().Get(name)
+		}
+		return ""
+	}
+}
+
+// MetadataKey keys the limiter by the metadata.
+func MetadataKey(name string) KeyFunc {
+	return func(ctx context.Context) string {
+		if md, ok := metadata.FromServerContext(ctx); ok {
+			return md.Get(name)
+		}
+		if md, ok := metadata.FromClientContext(ctx); ok {
+			return md.Get(name)
+		}
+		return ""
+	}
+}
+
+type entry struct {
+	limiter  ratelimit.Limiter
+	lastUsed time.Time
+}
+
+// keyed creates a limiter per key lazily, the limiters idle for a while are released.
+type keyed struct {
+	key        KeyFunc
+	newLimiter func(key string) ratelimit.Limiter
+
+	mu        sync.Mutex
+	limiters  map[string]*entry
+	lastSweep time.Time
+}
+
+func (k *keyed) get(ctx context.Context) ratelimit.Limiter {
+	key := k.key(ctx)
+	now := time.Now()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now.Sub(k.lastSweep) > idleTimeout {
+		for key, e := range k.limiters {
+			if now.Sub(e.lastUsed) > idleTimeout {
+				delete(k.limiters, key)
+			}
+		}
+		k.lastSweep = now
+	}
+	e, ok := k.limiters[key]
+	if !ok {
+		e = &entry{limiter: k.newLimiter(key)}
+		k.limiters[key] = e
+	}
+	e.lastUsed = now
+	return e.limiter
+}
@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/aegis/ratelimit"
+)
+
+// Quota is the state of a limiter, which is reported by the X-RateLimit-* headers.
+type Quota struct {
+	// Limit is the max requests.
+	Limit int64
+	// Remaining is the requests remaining.
+	Remaining int64
+	// Reset is the time until the quota is refilled.
+	Reset time.Duration
+}
+
+// QuotaLimiter is a limiter which reports its quota.
+type QuotaLimiter interface {
+	ratelimit.Limiter
+	Quota() Quota
+}
+
+var (
+	_ QuotaLimiter = (*TokenBucket)(nil)
+	_ QuotaLimiter = (*SlidingWindow)(nil)
+	_ QuotaLimiter = (*Concurrency)(nil)
+)
+
+func noop(ratelimit.DoneInfo) {}
+
+// TokenBucket is a token bucket limiter, the bucket of burst tokens is refilled at the rate per second.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewTokenBucket creates a token bucket limiter.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+func (b *TokenBucket) refill() {
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+// Allow takes a token.
+func (b *TokenBucket) Allow() (ratelimit.DoneFunc, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return nil, ratelimit.ErrLimitExceed
+	}
+	b.tokens--
+	return noop, nil
+}
+
+// Quota returns the tokens remaining, and the time until the next token.
+func (b *TokenBucket) Quota() Quota {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	q := Quota{Limit: int64(b.burst), Remaining: int64(b.tokens)}
+	if b.tokens < 1 && b.rate > 0 {
+		q.Reset = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	}
+	return q
+}
+
+// SlidingWindow is a sliding window limiter, which allows limit requests within the window.
+// The count of the sliding window is estimated by the current fixed window,
+// and the previous one weighted by its overlap.
+type SlidingWindow struct {
+	mu     sync.Mutex
+	limit  int64
+	window time.Duration
+	start  time.Time
+	prev   int64
+	curr   int64
+	now    func() time.Time
+}
+
+// NewSlidingWindow creates a sliding window limiter.
+func NewSlidingWindow(limit int64, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		limit:  limit,
+		window: window,
+		start:  time.Now().Truncate(window),
+		now:    time.Now,
+	}
+}
+
+// advance moves the fixed windows, and returns the estimated count.
+func (w *SlidingWindow) advance() (time.Time, float64) {
+	now := w.now()
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		if elapsed < 2*w.window {
+			w.prev = w.curr
+		} else {
+			w.prev = 0
+		}
+		w.curr = 0
+		w.start = now.Truncate(w.window)
+	}
+	overlap := 1 - float64(now.Sub(w.start))/float64(w.window)
+	return now, float64(w.prev)*overlap + float64(w.curr)
+}
+
+// Allow counts the request if the estimated count is under the limit.
+func (w *SlidingWindow) Allow() (ratelimit.DoneFunc, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, count := w.advance(); count+1 > float64(w.limit) {
+		return nil, ratelimit.ErrLimitExceed
+	}
+	w.curr++
+	return noop, nil
+}
+
+// Quota returns the requests remaining, and the time until the current fixed window ends.
+func (w *SlidingWindow) Quota() Quota {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now, count := w.advance()
+	remaining := w.limit - int64(math.Ceil(count))
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Quota{Limit: w.limit, Remaining: remaining, Reset: w.start.Add(w.window).Sub(now)}
+}
+
+// Concurrency is a limiter of the max concurrent requests.
+type Concurrency struct {
+	max      int64
+	inflight int64
+}
+
+// NewConcurrency creates a max concurrency limiter.
+func NewConcurrency(max int64) *Concurrency {
+	return &Concurrency{max: max}
+}
+
+// Allow admits the request if the in-flight requests are under the max.
+func (c *Concurrency) Allow() (ratelimit.DoneFunc, error) {
+	if atomic.AddInt64(&c.inflight, 1) > c.max {
+		atomic.AddInt64(&c.inflight, -1)
+		return nil, ratelimit.ErrLimitExceed
+	}
+	return func(ratelimit.DoneInfo) {
+		atomic.AddInt64(&c.inflight, -1)
+	}, nil
+}
+
+// Quota returns the concurrent requests remaining.
+func (c *Concurrency) Quota() Quota {
+	remaining := c.max - atomic.LoadInt64(&c.inflight)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Quota{Limit: c.max, Remaining: remaining}
+}
@@ -2,16 +2,27 @@ package ratelimit
 
 import (
 	"context"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/go-kratos/aegis/ratelimit"
 	"github.com/go-kratos/aegis/ratelimit/bbr"
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
 )
 
 // ErrLimitExceed is service unavailable due to rate limit exceeded.
 var ErrLimitExceed = errors.New(429, "RATELIMIT", "service unavailable due to rate limit exceeded")
 
+const (
+	headerLimit      = "X-RateLimit-Limit"
+	headerRemaining  = "X-RateLimit-Remaining"
+	headerReset      = "X-RateLimit-Reset"
+	headerRetryAfter = "Retry-After"
+)
+
 // Option is ratelimit option.
 type Option func(*options)
 
@@ -23,21 +34,54 @@ func WithLimiter(limiter ratelimit.Limiter) Option {
 	}
 }
 
+// WithKeyedLimiter with a limiter per key, which is created by newLimiter
+// for the key returned by the key func, e.g. per operation or per caller.
+// The limiters of different dimensions are combined by chaining the middlewares.
+func WithKeyedLimiter(key KeyFunc, newLimiter func(key string) ratelimit.Limiter) Option {
+	return func(o *options) {
+		o.keyed = &keyed{key: key, newLimiter: newLimiter, limiters: make(map[string]*entry)}
+	}
+}
+
+// WithHeaders with the X-RateLimit-* headers and the Retry-After header,
+// which are set on the reply header when the limiter reports its quota.
+func WithHeaders(enable bool) Option {
+	return func(o *options) {
+		o.headers = enable
+	}
+}
+
 type options struct {
 	limiter ratelimit.Limiter
+	keyed   *keyed
+	headers bool
+}
+
+func (o *options) get(ctx context.Context) ratelimit.Limiter {
+	if o.keyed != nil {
+		return o.keyed.get(ctx)
+	}
+	return o.limiter
 }
 
 // Server ratelimiter middleware
 func Server(opts ...Option) middleware.Middleware {
 	options := &options{
 		limiter: bbr.NewLimiter(),
+		headers: true,
 	}
 	for _, o := range opts {
 		o(options)
 	}
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
-			done, e := options.limiter.Allow()
+			limiter := options.get(ctx)
+			done, e := limiter.Allow()
+			if options.headers {
+				if tr, ok := transport.FromServerContext(ctx); ok {
+					setHeaders(tr.ReplyHeader(), limiter, e != nil)
+				}
+			}
 			if e != nil {
 				// rejected
 				return nil, ErrLimitExceed
@@ -49,3 +93,53 @@ func Server(opts ...Option) middleware.Middleware {
 		}
 	}
 }
+
+// Client is a client ratelimiter middleware, the requests over the limit
+// are rejected locally without being sent. There is no default limiter,
+// which must be set by WithLimiter or WithKeyedLimiter.
+func Client(opts ...Option) middleware.Middleware {
+	options := &options{}
+	for _, o := range opts {
+		o(options)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			limiter := options.get(ctx)
+			if limiter == nil {
+				return handler(ctx, req)
+			}
+			done, e := limiter.Allow()
+			if e != nil {
+				return nil, ErrLimitExceed
+			}
+			reply, err = handler(ctx, req)
+			done(ratelimit.DoneInfo{Err: err})
+			return
+		}
+	}
+}
+
+func setHeaders(header transport.Header, limiter ratelimit.Limiter, rejected bool) {
+	l, ok := limiter.(QuotaLimiter)
+	if !ok {
+		return
+	}
+	q := l.Quota()
+	header.Set(headerLimit, strconv.FormatInt(q.Limit, 10))
+	header.Set(headerRemaining, strconv.FormatInt(q.Remaining, 10))
+	if q.Reset > 0 {
+		header.Set(headerReset, strconv.FormatInt(seconds(q.Reset), 10))
+	}
+	if rejected {
+		retryAfter := seconds(q.Reset)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		header.Set(headerRetryAfter, strconv.FormatInt(retryAfter, 10))
+	}
+}
+
+// seconds rounds the duration up to seconds.
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
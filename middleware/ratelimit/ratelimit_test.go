@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/aegis/ratelimit"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type Transport struct {
+	transport.Transporter
+	operation string
+	reqHeader headerCarrier
+	repHeader headerCarrier
+}
+
+func (tr *Transport) Operation() string               { return tr.operation }
+func (tr *Transport) RequestHeader() transport.Header { return tr.reqHeader }
+func (tr *Transport) ReplyHeader() transport.Header   { return tr.repHeader }
+
+func newTransport(operation string) *Transport {
+	return &Transport{operation: operation, reqHeader: headerCarrier{}, repHeader: headerCarrier{}}
+}
+
+func handler(ctx context.Context, req interface{}) (interface{}, error) {
+	return "reply", nil
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := NewTokenBucket(2, 2)
+	b.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if _, err := b.Allow(); err != nil {
+			t.Fatalf("expect allowed, got %v", err)
+		}
+	}
+	if _, err := b.Allow(); err != ratelimit.ErrLimitExceed {
+		t.Errorf("expect %v, got %v", ratelimit.ErrLimitExceed, err)
+	}
+	if q := b.Quota(); q.Remaining != 0 || q.Reset != 500*time.Millisecond {
+		t.Errorf("expect 0 remaining and 500ms reset, got %+v", q)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if _, err := b.Allow(); err != nil {
+		t.Errorf("expect allowed after refill, got %v", err)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	now := time.Unix(100, 0)
+	w := NewSlidingWindow(4, time.Second)
+	w.now = func() time.Time { return now }
+	w.start = now
+	for i := 0; i < 4; i++ {
+		if _, err := w.Allow(); err != nil {
+			t.Fatalf("expect allowed, got %v", err)
+		}
+	}
+	if _, err := w.Allow(); err != ratelimit.ErrLimitExceed {
+		t.Errorf("expect %v, got %v", ratelimit.ErrLimitExceed, err)
+	}
+	// half of the previous window overlaps: 4*0.5 = 2 requests are counted.
+	now = now.Add(1500 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err := w.Allow(); err != nil {
+			t.Fatalf("expect allowed, got %v", err)
+		}
+	}
+	if _, err := w.Allow(); err != ratelimit.ErrLimitExceed {
+		t.Errorf("expect %v, got %v", ratelimit.ErrLimitExceed, err)
+	}
+	if q := w.Quota(); q.Remaining != 0 || q.Reset != 500*time.Millisecond {
+		t.Errorf("expect 0 remaining and 500ms reset, got %+v", q)
+	}
+	now = now.Add(2 * time.Second)
+	if q := w.Quota(); q.Remaining != 4 {
+		t.Errorf("expect 4 remaining, got %+v", q)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	c := NewConcurrency(1)
+	done, err := c.Allow()
+	if err != nil {
+		t.Fatalf("expect allowed, got %v", err)
+	}
+	if _, err = c.Allow(); err != ratelimit.ErrLimitExceed {
+		t.Errorf("expect %v, got %v", ratelimit.ErrLimitExceed, err)
+	}
+	done(ratelimit.DoneInfo{})
+	if _, err = c.Allow(); err != nil {
+		t.Errorf("expect allowed, got %v", err)
+	}
+}
+
+func TestServerHeaders(t *testing.T) {
+	now := time.Now()
+	b := NewTokenBucket(1, 1)
+	b.now = func() time.Time { return now }
+	h := Server(WithLimiter(b))(handler)
+
+	tr := newTransport("/test.Service/Get")
+	if _, err := h(transport.NewServerContext(context.Background(), tr), nil); err != nil {
+		t.Fatal(err)
+	}
+	if v := tr.repHeader.Get(headerLimit); v != "1" {
+		t.Errorf("expect %v, got %v", "1", v)
+	}
+	if v := tr.repHeader.Get(headerRemaining); v != "0" {
+		t.Errorf("expect %v, got %v", "0", v)
+	}
+
+	tr = newTransport("/test.Service/Get")
+	_, err := h(transport.NewServerContext(context.Background(), tr), nil)
+	if !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expect %v, got %v", ErrLimitExceed, err)
+	}
+	if v := tr.repHeader.Get(headerRetryAfter); v != "1" {
+		t.Errorf("expect %v, got %v", "1", v)
+	}
+	if v := tr.repHeader.Get(headerReset); v != "1" {
+		t.Errorf("expect %v, got %v", "1", v)
+	}
+}
+
+func TestServerKeyed(t *testing.T) {
+	var created []string
+	h := Server(WithKeyedLimiter(HeaderKey("X-User"), func(key string) ratelimit.Limiter {
+		created = append(created, key)
+		return NewConcurrency(0)
+	}))(handler)
+	for _, user := range []string{"alice", "bob", "alice"} {
+		tr := newTransport("/test.Service/Get")
+		tr.reqHeader.Set("X-User", user)
+		if _, err := h(transport.NewServerContext(context.Background(), tr), nil); !errors.Is(err, ErrLimitExceed) {
+			t.Errorf("expect %v, got %v", ErrLimitExceed, err)
+		}
+	}
+	if len(created) != 2 {
+		t.Errorf("expect %v, got %v", 2, created)
+	}
+}
+
+func TestClient(t *testing.T) {
+	h := Client()(handler)
+	if _, err := h(context.Background(), nil); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+	h = Client(WithKeyedLimiter(OperationKey, func(string) ratelimit.Limiter {
+		return NewTokenBucket(0, 1)
+	}))(handler)
+	for _, c := range []struct {
+		operation string
+		err       error
+	}{
+		{"/test.Service/Get", nil},
+		{"/test.Service/Get", ErrLimitExceed},
+		{"/test.Service/List", nil},
+	} {
+		ctx := transport.NewClientContext(context.Background(), newTransport(c.operation))
+		if _, err := h(ctx, nil); err != c.err {
+			t.Errorf("expect %v, got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/go-kratos/aegis/pkg/window"
+)
+
+// Budget limits the retries to a ratio of the requests within a rolling window,
+// so that a degraded service is not amplified by retries.
+type Budget struct {
+	ratio    float64
+	min      int64
+	requests window.RollingCounter
+	retries  window.RollingCounter
+}
+
+// NewBudget new a retry budget, which allows minRetries plus ratio
+// times the number of requests to be retried within the window.
+func NewBudget(ratio float64, minRetries int64, win time.Duration) *Budget {
+	const buckets = 10
+	opts := window.RollingCounterOpts{
+		Size:           buckets,
+		BucketDuration: win / buckets,
+	}
+	return &Budget{
+		ratio:    ratio,
+		min:      minRetries,
+		requests: window.NewRollingCounter(opts),
+		retries:  window.NewRollingCounter(opts),
+	}
+}
+
+func (b *Budget) request() {
+	b.requests.Add(1)
+}
+
+func (b *Budget) allow() bool {
+	limit := b.ratio*float64(b.requests.Value()) + float64(b.min)
+	if float64(b.retries.Value()) >= limit {
+		return false
+	}
+	b.retries.Add(1)
+	return true
+}
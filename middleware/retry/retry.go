@@ -0,0 +1,177 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+// Backoff returns the duration to wait before the next attempt.
+type Backoff func(attempt int) time.Duration
+
+// Option is retry option.
+type Option func(*options)
+
+// WithAttempts with max attempts, including the first call.
+func WithAttempts(n int) Option {
+	return func(o *options) {
+		o.attempts = n
+	}
+}
+
+// WithBackoff with backoff between attempts.
+func WithBackoff(b Backoff) Option {
+	return func(o *options) {
+		o.backoff = b
+	}
+}
+
+// WithCodes with retryable error codes.
+func WithCodes(codes ...int) Option {
+	return func(o *options) {
+		o.codes = codes
+	}
+}
+
+// WithReasons with retryable error reasons.
+func WithReasons(reasons ...string) Option {
+	return func(o *options) {
+		o.reasons = reasons
+	}
+}
+
+// WithRetryable with a custom retryable predicate,
+// which takes precedence over the codes and reasons.
+func WithRetryable(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.retryable = fn
+	}
+}
+
+// WithPerAttemptTimeout with the timeout of each attempt.
+func WithPerAttemptTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithBudget with retry budget.
+func WithBudget(b *Budget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+type options struct {
+	attempts  int
+	backoff   Backoff
+	codes     []int
+	reasons   []string
+	retryable func(err error) bool
+	timeout   time.Duration
+	budget    *Budget
+}
+
+// Exponential returns an exponential backoff with full jitter,
+// the wait is picked randomly in [0, min(max, base * 2^attempt)).
+func Exponential(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := max
+		if attempt < 32 {
+			if v := base << uint(attempt); v > 0 && v < max {
+				d = v
+			}
+		}
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d)))
+	}
+}
+
+type attemptKey struct{}
+
+// AttemptFromContext returns the attempt number stored in ctx, starting from 0.
+func AttemptFromContext(ctx context.Context) (attempt int, ok bool) {
+	attempt, ok = ctx.Value(attemptKey{}).(int)
+	return
+}
+
+// Client is a client retry middleware.
+// It should be placed before the tracing and metrics middleware,
+// so that every attempt is visible to them. Each attempt re-runs the
+// selector, so a different node can be picked.
+func Client(opts ...Option) middleware.Middleware {
+	o := &options{
+		attempts: 3,
+		backoff:  Exponential(25*time.Millisecond, time.Second),
+		codes:    []int{503},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.retryable == nil {
+		o.retryable = o.match
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			if o.budget != nil {
+				o.budget.request()
+			}
+			for attempt := 0; ; attempt++ {
+				var timeout bool
+				reply, timeout, err = o.do(ctx, attempt, handler, req)
+				if err == nil || attempt+1 >= o.attempts || ctx.Err() != nil {
+					return
+				}
+				if !timeout && !o.retryable(err) {
+					return
+				}
+				if o.budget != nil && !o.budget.allow() {
+					return
+				}
+				t := time.NewTimer(o.backoff(attempt))
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					return
+				}
+			}
+		}
+	}
+}
+
+// do runs a single attempt, and reports whether it exceeded the per-attempt timeout.
+func (o *options) do(ctx context.Context, attempt int, handler middleware.Handler, req interface{}) (interface{}, bool, error) {
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
+	if o.timeout <= 0 {
+		reply, err := handler(ctx, req)
+		return reply, false, err
+	}
+	actx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+	reply, err := handler(actx, req)
+	return reply, err != nil && actx.Err() == context.DeadlineExceeded && ctx.Err() == nil, err
+}
+
+func (o *options) match(err error) bool {
+	se := errors.FromError(err)
+	if se == nil {
+		return false
+	}
+	for _, code := range o.codes {
+		if int(se.Code) == code {
+			return true
+		}
+	}
+	for _, reason := range o.reasons {
+		if se.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
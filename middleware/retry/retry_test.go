@@ -0,0 +1,103 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+func noBackoff(int) time.Duration { return 0 }
+
+func TestRetry(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		attempt, ok := AttemptFromContext(ctx)
+		if !ok || attempt != calls {
+			t.Errorf("expect attempt %d, got %d", calls, attempt)
+		}
+		calls++
+		if calls < 3 {
+			return nil, errors.ServiceUnavailable("UNAVAILABLE", "")
+		}
+		return "ok", nil
+	}
+	reply, err := Client(WithAttempts(3), WithBackoff(noBackoff))(next)(context.Background(), "req")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "ok" || calls != 3 {
+		t.Errorf("expect %s after %d calls, got %v after %d calls", "ok", 3, reply, calls)
+	}
+}
+
+func TestNotRetryable(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.BadRequest("BAD", "")
+	}
+	_, err := Client(WithBackoff(noBackoff))(next)(context.Background(), "req")
+	if !errors.IsBadRequest(err) {
+		t.Errorf("expect bad request, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expect %d calls, got %d", 1, calls)
+	}
+}
+
+func TestReasons(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.Conflict("ABORTED", "")
+	}
+	_, _ = Client(WithAttempts(2), WithReasons("ABORTED"), WithBackoff(noBackoff))(next)(context.Background(), "req")
+	if calls != 2 {
+		t.Errorf("expect %d calls, got %d", 2, calls)
+	}
+}
+
+func TestPerAttemptTimeout(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return "ok", nil
+	}
+	reply, err := Client(WithPerAttemptTimeout(10*time.Millisecond), WithRetryable(func(error) bool { return false }), WithBackoff(noBackoff))(next)(context.Background(), "req")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "ok" || calls != 2 {
+		t.Errorf("expect %s after %d calls, got %v after %d calls", "ok", 2, reply, calls)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.ServiceUnavailable("UNAVAILABLE", "")
+	}
+	m := Client(WithAttempts(5), WithBudget(NewBudget(0, 2, time.Second)), WithBackoff(noBackoff))(next)
+	_, _ = m(context.Background(), "req")
+	_, _ = m(context.Background(), "req")
+	// two requests and two retries allowed by the budget
+	if calls != 4 {
+		t.Errorf("expect %d calls, got %d", 4, calls)
+	}
+}
+
+func TestExponential(t *testing.T) {
+	b := Exponential(10*time.Millisecond, 50*time.Millisecond)
+	for attempt := 0; attempt < 64; attempt++ {
+		if d := b(attempt); d < 0 || d >= 50*time.Millisecond {
+			t.Errorf("unexpected backoff %s for attempt %d", d, attempt)
+		}
+	}
+}
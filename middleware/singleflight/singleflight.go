@@ -0,0 +1,143 @@
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	icontext "github.com/go-kratos/kratos/v2/internal/context"
+	"github.com/go-kratos/kratos/v2/internal/protoutil"
+	ireply "github.com/go-kratos/kratos/v2/internal/reply"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// KeyFunc returns the key of a request, the requests with the same key are coalesced,
+// a request with an empty key is not coalesced.
+type KeyFunc func(ctx context.Context, req interface{}) string
+
+// Option is singleflight option.
+type Option func(*options)
+
+// WithKeyFunc with the key func, the default key is the operation
+// with the hash of the deterministic proto encoding of the request.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(o *options) {
+		o.key = fn
+	}
+}
+
+type options struct {
+	key KeyFunc
+}
+
+// Server is a server middleware which collapses the concurrent identical requests
+// into one handler execution, the reply is shared by all the callers, so it must not be modified.
+// The execution carries the values of the first caller's context, such as the transport,
+// and it's canceled only when all the callers are canceled.
+func Server(opts ...Option) middleware.Middleware {
+	return coalesce(func(ctx context.Context) (transport.Transporter, bool) {
+		return transport.FromServerContext(ctx)
+	}, opts...)
+}
+
+// Client is a client middleware which collapses the concurrent identical requests
+// into one call, the reply is copied into the reply of every caller.
+// The call carries the values of the first caller's context, such as the transport,
+// and it's canceled only when all the callers are canceled.
+func Client(opts ...Option) middleware.Middleware {
+	return coalesce(func(ctx context.Context) (transport.Transporter, bool) {
+		return transport.FromClientContext(ctx)
+	}, opts...)
+}
+
+func coalesce(from func(context.Context) (transport.Transporter, bool), opts ...Option) middleware.Middleware {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.key == nil {
+		o.key = func(ctx context.Context, req interface{}) string {
+			tr, ok := from(ctx)
+			if !ok {
+				return ""
+			}
+			return protoutil.Key(tr.Operation(), req)
+		}
+	}
+	g := &group{calls: make(map[string]*call)}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			key := o.key(ctx, req)
+			if key == "" {
+				return handler(ctx, req)
+			}
+			return g.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+				// the client transports decode into the reply of the first caller by default
+				return handler(ireply.NewIsolatedContext(ctx), req)
+			})
+		}
+	}
+}
+
+type call struct {
+	done    chan struct{}
+	reply   interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func (g *group) do(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	c, ok := g.calls[key]
+	if !ok {
+		cctx, cancel := context.WithCancel(icontext.Detach(ctx))
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.run(cctx, key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.reply, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// nobody waits for the result any more
+			c.cancel()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (g *group) run(ctx context.Context, key string, c *call, fn func(context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("singleflight: panic: %v", r)
+			c.err = errors.InternalServer("SINGLEFLIGHT_PANIC", fmt.Sprintf("panic triggered: %v", r))
+		}
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		c.cancel()
+		close(c.done)
+	}()
+	c.reply, c.err = fn(ctx)
+}
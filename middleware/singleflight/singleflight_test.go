@@ -0,0 +1,162 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type Transport struct {
+	transport.Transporter
+	operation string
+}
+
+func (tr *Transport) Operation() string {
+	return tr.operation
+}
+
+func TestServer(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wrapperspb.String("reply " + req.(*wrapperspb.StringValue).Value), nil
+	}
+	h := Server()(next)
+	ctx := transport.NewServerContext(context.Background(), &Transport{operation: "/test.Service/Get"})
+
+	var wg sync.WaitGroup
+	replies := make([]interface{}, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replies[i], _ = h(ctx, wrapperspb.String("a"))
+		}(i)
+	}
+	// a different request
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = h(ctx, wrapperspb.String("b"))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expect %v, got %v", 2, n)
+	}
+	for _, reply := range replies {
+		if reply.(*wrapperspb.StringValue).Value != "reply a" {
+			t.Errorf("expect %v, got %v", "reply a", reply)
+		}
+	}
+}
+
+func TestServer_Bypass(t *testing.T) {
+	var calls int32
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return req, nil
+	}
+	h := Server(WithKeyFunc(func(context.Context, interface{}) string { return "" }))(next)
+	for i := 0; i < 3; i++ {
+		if _, err := h(context.Background(), "req"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("expect %v, got %v", 3, n)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	canceled := make(chan struct{})
+	release := make(chan struct{})
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			close(canceled)
+			return nil, ctx.Err()
+		case <-release:
+			return "reply", nil
+		}
+	}
+	h := Server(WithKeyFunc(func(context.Context, interface{}) string { return "key" }))(next)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := h(ctx1, nil)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		_, err := h(ctx2, nil)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the first caller leaves, the execution goes on for the second one
+	cancel1()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expect %v, got %v", context.Canceled, err)
+	}
+	select {
+	case <-canceled:
+		t.Fatal("expect the execution not to be canceled")
+	case <-time.After(10 * time.Millisecond):
+	}
+	// all callers leave, the execution is canceled
+	cancel2()
+	<-errs
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expect the execution to be canceled")
+	}
+	close(release)
+}
+
+func TestClient(t *testing.T) {
+	release := make(chan struct{})
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-release
+		return wrapperspb.String("reply"), nil
+	}
+	h := Client()(next)
+	ctx := transport.NewClientContext(context.Background(), &Transport{operation: "/test.Service/Get"})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply, err := h(ctx, wrapperspb.String("a"))
+			if err != nil || reply.(*wrapperspb.StringValue).Value != "reply" {
+				t.Errorf("expect %v, got %v %v", "reply", reply, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestPanic(t *testing.T) {
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	}
+	h := Server(WithKeyFunc(func(context.Context, interface{}) string { return "key" }))(next)
+	if _, err := h(context.Background(), nil); err == nil {
+		t.Errorf("expect error")
+	}
+}
@@ -0,0 +1,303 @@
+package throttling
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/aegis/pkg/window"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+const (
+	resultAllowed  = "allowed"
+	resultRejected = "rejected"
+)
+
+// ErrNotAllowed is request rejected locally by the adaptive throttling.
+var ErrNotAllowed = errors.New(503, "THROTTLED", "request rejected by client-side throttling")
+
+// Fallback returns a degraded reply for the request which is rejected.
+type Fallback func(ctx context.Context, req interface{}, err error) (interface{}, error)
+
+// Option is throttling option.
+type Option func(*options)
+
+// WithK with the multiplier K, the requests are rejected when they exceed K times the accepts.
+// Reducing the K makes the throttling more aggressive.
+func WithK(k float64) Option {
+	return func(o *options) {
+		o.k = k
+	}
+}
+
+// WithWindow with the window of the request statistics.
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// WithBuckets with the number of buckets of the window.
+func WithBuckets(n int) Option {
+	return func(o *options) {
+		o.buckets = n
+	}
+}
+
+// WithMinRequests with the minimum requests within the window before throttling.
+func WithMinRequests(n int64) Option {
+	return func(o *options) {
+		o.minRequests = n
+	}
+}
+
+// WithErrorHandler with the func which reports whether the error is a failure,
+// which is not accepted by the backend.
+func WithErrorHandler(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.isFailure = fn
+	}
+}
+
+// WithFallback with the fallback which is called when the request is rejected,
+// instead of returning ErrNotAllowed.
+func WithFallback(fn Fallback) Option {
+	return func(o *options) {
+		o.fallback = fn
+	}
+}
+
+// WithRequests with the counter of the requests,
+// which is labeled by the operation, the target and the result, allowed or rejected,
+// the target of the rejected requests is empty.
+func WithRequests(c metrics.Counter) Option {
+	return func(o *options) {
+		o.requests = c
+	}
+}
+
+type options struct {
+	k           float64
+	window      time.Duration
+	buckets     int
+	minRequests int64
+	isFailure   func(err error) bool
+	fallback    Fallback
+	requests    metrics.Counter
+}
+
+// isFailure is the default error handler, which treats the errors
+// of an overloaded or unavailable backend as failures.
+func isFailure(err error) bool {
+	return errors.IsInternalServer(err) || errors.IsServiceUnavailable(err) || errors.IsGatewayTimeout(err)
+}
+
+// Client is a client-side adaptive throttling middleware described in the Google SRE book,
+// the request is rejected locally with the probability max(0, (requests - K * accepts) / (requests + 1)).
+// The statistics are kept per operation, and per operation and target node,
+// the nodes which are throttled for the operation are filtered out by the selector.
+func Client(opts ...Option) middleware.Middleware {
+	o := options{
+		k:           2,
+		window:      10 * time.Second,
+		buckets:     10,
+		minRequests: 100,
+		isFailure:   isFailure,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.k <= 0 {
+		o.k = 2
+	}
+	if o.buckets <= 0 {
+		o.buckets = 10
+	}
+	if o.window/time.Duration(o.buckets) <= 0 {
+		o.window = 10 * time.Second
+		o.buckets = 10
+	}
+	t := &throttling{opts: o, throttlers: make(map[string]*throttler)}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var operation string
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				operation = tr.Operation()
+			}
+			th := t.get(operation)
+			if !th.allow() {
+				// the requests rejected locally are counted,
+				// so that the rejection probability keeps growing under overload.
+				th.mark(false)
+				return t.reject(ctx, req, operation, "")
+			}
+			a := &attempt{t: t, operation: operation}
+			reply, err := handler(selector.NewSelectOptionsContext(ctx,
+				selector.WithFilter(a.filter),
+				selector.WithOnPicked(a.picked),
+			), req)
+			if a.throttled() && errors.Is(err, selector.ErrNoAvailable) {
+				return t.reject(ctx, req, operation, "")
+			}
+			accepted := !o.isFailure(err)
+			th.mark(accepted)
+			for _, target := range a.targets() {
+				t.get(operation + "@" + target).mark(accepted)
+			}
+			t.count(operation, a.target(), resultAllowed)
+			return reply, err
+		}
+	}
+}
+
+type throttling struct {
+	opts options
+
+	mu         sync.Mutex
+	throttlers map[string]*throttler
+	lastSweep  time.Time
+}
+
+// get returns the throttler of the key, the throttlers idle for longer than the window
+// are released, since their statistics have all expired.
+func (t *throttling) get(key string) *throttler {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if now.Sub(t.lastSweep) > t.opts.window {
+		for key, th := range t.throttlers {
+			if now.Sub(th.lastUsed) > t.opts.window {
+				delete(t.throttlers, key)
+			}
+		}
+		t.lastSweep = now
+	}
+	th, ok := t.throttlers[key]
+	if !ok {
+		th = newThrottler(t.opts)
+		t.throttlers[key] = th
+	}
+	th.lastUsed = now
+	return th
+}
+
+func (t *throttling) reject(ctx context.Context, req interface{}, operation, target string) (interface{}, error) {
+	t.count(operation, target, resultRejected)
+	if t.opts.fallback != nil {
+		return t.opts.fallback(ctx, req, ErrNotAllowed)
+	}
+	return nil, ErrNotAllowed
+}
+
+func (t *throttling) count(operation, target, result string) {
+	if t.opts.requests != nil {
+		t.opts.requests.With(operation, target, result).Inc()
+	}
+}
+
+// attempt filters out the throttled nodes, and records the picked ones.
+type attempt struct {
+	t         *throttling
+	operation string
+
+	mu       sync.Mutex
+	rejected bool
+	addrs    []string
+}
+
+func (a *attempt) filter(_ context.Context, nodes []selector.Node) []selector.Node {
+	newNodes := make([]selector.Node, 0, len(nodes))
+	for _, n := range nodes {
+		// the rejections of a node are not counted, it's picked again
+		// once its failures fall out of the window.
+		if a.t.get(a.operation + "@" + n.Address()).allow() {
+			newNodes = append(newNodes, n)
+		}
+	}
+	if len(newNodes) == 0 && len(nodes) > 0 {
+		a.mu.Lock()
+		a.rejected = true
+		a.mu.Unlock()
+	}
+	return newNodes
+}
+
+func (a *attempt) picked(n selector.Node) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addrs = append(a.addrs, n.Address())
+}
+
+func (a *attempt) throttled() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rejected && len(a.addrs) == 0
+}
+
+func (a *attempt) targets() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.addrs
+}
+
+// target returns the last picked node.
+func (a *attempt) target() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.addrs) == 0 {
+		return ""
+	}
+	return a.addrs[len(a.addrs)-1]
+}
+
+// throttler keeps the requests and the accepts within the window.
+type throttler struct {
+	k           float64
+	minRequests int64
+	requests    window.RollingCounter
+	accepts     window.RollingCounter
+	// lastUsed is guarded by the mutex of the throttling.
+	lastUsed time.Time
+
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newThrottler(o options) *throttler {
+	opts := window.RollingCounterOpts{Size: o.buckets, BucketDuration: o.window / time.Duration(o.buckets)}
+	return &throttler{
+		k:           o.k,
+		minRequests: o.minRequests,
+		requests:    window.NewRollingCounter(opts),
+		accepts:     window.NewRollingCounter(opts),
+		r:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (t *throttler) allow() bool {
+	requests := t.requests.Value()
+	if requests < t.minRequests {
+		return true
+	}
+	p := math.Max(0, (float64(requests)-t.k*float64(t.accepts.Value()))/float64(requests+1))
+	if p == 0 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.r.Float64() >= p
+}
+
+func (t *throttler) mark(accepted bool) {
+	t.requests.Add(1)
+	if accepted {
+		t.accepts.Add(1)
+	}
+}
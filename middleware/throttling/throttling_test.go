@@ -0,0 +1,180 @@
+package throttling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/random"
+)
+
+type mockCounter struct {
+	lvs    []string
+	counts map[string]int
+}
+
+func (m *mockCounter) With(lvs ...string) metrics.Counter {
+	m.lvs = lvs
+	return m
+}
+
+func (m *mockCounter) Inc() { m.Add(1) }
+
+func (m *mockCounter) Add(v float64) {
+	if m.counts == nil {
+		m.counts = make(map[string]int)
+	}
+	m.counts[m.lvs[1]+"/"+m.lvs[2]] += int(v)
+}
+
+func newSelector(addrs ...string) selector.Selector {
+	s := random.New()
+	nodes := make([]selector.Node, 0, len(addrs))
+	for _, addr := range addrs {
+		nodes = append(nodes, selector.NewNode("http", addr, &registry.ServiceInstance{}))
+	}
+	s.Apply(nodes)
+	return s
+}
+
+func TestThrottler(t *testing.T) {
+	th := newThrottler(options{k: 2, window: 10 * time.Second, buckets: 10, minRequests: 10})
+	for i := 0; i < 10; i++ {
+		th.mark(true)
+	}
+	if !th.allow() {
+		t.Errorf("expect allowed")
+	}
+	for i := 0; i < 1000; i++ {
+		th.mark(false)
+	}
+	var rejected int
+	for i := 0; i < 1000; i++ {
+		if !th.allow() {
+			rejected++
+		}
+	}
+	// the rejection probability is (1010 - 2 * 10) / 1011.
+	if rejected < 900 {
+		t.Errorf("expect most requests rejected, got %d", rejected)
+	}
+}
+
+func TestClientFallback(t *testing.T) {
+	failed := errors.ServiceUnavailable("UNAVAILABLE", "")
+	var calls int
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return nil, failed
+	}
+	counter := &mockCounter{}
+	h := Client(
+		WithMinRequests(10),
+		WithRequests(counter),
+		WithFallback(func(ctx context.Context, req interface{}, err error) (interface{}, error) {
+			if !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("expect %v, got %v", ErrNotAllowed, err)
+			}
+			return "degraded", nil
+		}),
+	)(next)
+	var degraded int
+	for i := 0; i < 200; i++ {
+		reply, err := h(context.Background(), "req")
+		if err == nil && reply == "degraded" {
+			degraded++
+		}
+	}
+	if calls+degraded != 200 || degraded < 150 {
+		t.Errorf("expect most requests degraded, got %d calls and %d degraded", calls, degraded)
+	}
+	if counter.counts["/"+resultRejected] != degraded || counter.counts["/"+resultAllowed] != calls {
+		t.Errorf("expect %d rejected and %d allowed, got %v", degraded, calls, counter.counts)
+	}
+}
+
+func TestClientErrorHandler(t *testing.T) {
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.ServiceUnavailable("UNAVAILABLE", "")
+	}
+	h := Client(WithMinRequests(10), WithErrorHandler(func(err error) bool { return false }))(next)
+	for i := 0; i < 200; i++ {
+		if _, err := h(context.Background(), "req"); errors.Is(err, ErrNotAllowed) {
+			t.Fatalf("expect not throttled")
+		}
+	}
+}
+
+func TestClientTarget(t *testing.T) {
+	s := newSelector("bad", "good")
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		n, _, err := s.Select(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if n.Address() == "bad" {
+			return nil, errors.InternalServer("INTERNAL", "")
+		}
+		return n.Address(), nil
+	}
+	// K is large enough to keep the operation from being throttled.
+	h := Client(WithMinRequests(10), WithK(1000))(next)
+	counts := make(map[string]int)
+	for i := 0; i < 500; i++ {
+		reply, err := h(context.Background(), "req")
+		if err == nil {
+			counts[reply.(string)]++
+		} else {
+			counts["bad"]++
+		}
+	}
+	if counts["bad"] > 100 {
+		t.Errorf("expect the bad node throttled, got %v", counts)
+	}
+}
+
+func TestClientAllTargetsThrottled(t *testing.T) {
+	s := newSelector("bad")
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if _, _, err := s.Select(ctx); err != nil {
+			return nil, err
+		}
+		return nil, errors.InternalServer("INTERNAL", "")
+	}
+	h := Client(WithMinRequests(10), WithK(1000))(next)
+	var rejected int
+	for i := 0; i < 200; i++ {
+		if _, err := h(context.Background(), "req"); errors.Is(err, ErrNotAllowed) {
+			rejected++
+		}
+	}
+	if rejected == 0 {
+		t.Errorf("expect rejected when all the nodes are throttled")
+	}
+}
+
+func TestClientInvalidOptions(t *testing.T) {
+	h := Client(WithBuckets(0), WithWindow(0), WithK(-1))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	if reply, err := h(context.Background(), "req"); err != nil || reply != "ok" {
+		t.Errorf("expect %v, got %v %v", "ok", reply, err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	th := &throttling{opts: options{k: 2, window: 10 * time.Millisecond, buckets: 10}, throttlers: make(map[string]*throttler)}
+	th.get("/test.Service/Get@a")
+	time.Sleep(20 * time.Millisecond)
+	th.get("/test.Service/Get@b")
+	if _, ok := th.throttlers["/test.Service/Get@a"]; ok {
+		t.Errorf("expect the idle throttler released")
+	}
+	if _, ok := th.throttlers["/test.Service/Get@b"]; !ok {
+		t.Errorf("expect the throttler kept")
+	}
+}
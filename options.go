@@ -34,7 +34,15 @@ type options struct {
 	registrarTimeout time.Duration
 	// 默认 10s
 	stopTimeout      time.Duration
+	// 注销实例后继续提供服务的时间，默认 0
+	drainDelay       time.Duration
 	servers          []transport.Server
+
+	// 生命周期钩子
+	beforeStart []func(context.Context) error
+	beforeStop  []func(context.Context) error
+	afterStart  []func(context.Context) error
+	afterStop   []func(context.Context) error
 }
 
 // ID with service id.
@@ -96,3 +104,37 @@ func RegistrarTimeout(t time.Duration) Option {
 func StopTimeout(t time.Duration) Option {
 	return func(o *options) { o.stopTimeout = t }
 }
+
+// DrainDelay with the delay between deregistration and stopping the servers,
+// the servers are drained and keep serving while the deregistration propagates.
+func DrainDelay(t time.Duration) Option {
+	return func(o *options) { o.drainDelay = t }
+}
+
+// BeforeStart run funcs before app starts.
+func BeforeStart(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.beforeStart = append(o.beforeStart, fn)
+	}
+}
+
+// BeforeStop run funcs before app stops.
+func BeforeStop(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.beforeStop = append(o.beforeStop, fn)
+	}
+}
+
+// AfterStart run funcs after app starts.
+func AfterStart(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.afterStart = append(o.afterStart, fn)
+	}
+}
+
+// AfterStop run funcs after app stops.
+func AfterStop(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.afterStop = append(o.afterStop, fn)
+	}
+}
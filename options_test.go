@@ -143,3 +143,57 @@ func TestRegistrarTimeout(t *testing.T) {
 		t.Fatal("o.registrarTimeout is not equal to v")
 	}
 }
+
+func TestBeforeStart(t *testing.T) {
+	o := &options{}
+	v := func(_ context.Context) error {
+		return nil
+	}
+	BeforeStart(v)(o)
+	BeforeStart(v)(o)
+	if len(o.beforeStart) != 2 {
+		t.Fatalf("len(o.beforeStart):%d is not equal to 2", len(o.beforeStart))
+	}
+}
+
+func TestBeforeStop(t *testing.T) {
+	o := &options{}
+	v := func(_ context.Context) error {
+		return nil
+	}
+	BeforeStop(v)(o)
+	if len(o.beforeStop) != 1 {
+		t.Fatalf("len(o.beforeStop):%d is not equal to 1", len(o.beforeStop))
+	}
+}
+
+func TestAfterStart(t *testing.T) {
+	o := &options{}
+	v := func(_ context.Context) error {
+		return nil
+	}
+	AfterStart(v)(o)
+	if len(o.afterStart) != 1 {
+		t.Fatalf("len(o.afterStart):%d is not equal to 1", len(o.afterStart))
+	}
+}
+
+func TestAfterStop(t *testing.T) {
+	o := &options{}
+	v := func(_ context.Context) error {
+		return nil
+	}
+	AfterStop(v)(o)
+	if len(o.afterStop) != 1 {
+		t.Fatalf("len(o.afterStop):%d is not equal to 1", len(o.afterStop))
+	}
+}
+
+func TestDrainDelay(t *testing.T) {
+	o := &options{}
+	v := time.Duration(123)
+	DrainDelay(v)(o)
+	if !reflect.DeepEqual(v, o.drainDelay) {
+		t.Fatal("o.drainDelay is not equal to v")
+	}
+}
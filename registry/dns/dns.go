@@ -0,0 +1,209 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var _ registry.Discovery = (*Discovery)(nil)
+
+// Option is dns discovery option.
+type Option func(o *options)
+
+// WithInterval with the interval of resolving.
+func WithInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// WithResolver with the dns resolver.
+func WithResolver(r *net.Resolver) Option {
+	return func(o *options) {
+		o.resolver = r
+	}
+}
+
+// WithSchemes with the schemes of the instance endpoints, which is required
+// for the host with port names, as the port serves a single protocol.
+// The scheme of a SRV name is its service label by default, e.g. grpc of _grpc._tcp.example.com.
+func WithSchemes(schemes ...string) Option {
+	return func(o *options) {
+		o.schemes = schemes
+	}
+}
+
+type options struct {
+	interval time.Duration
+	resolver *net.Resolver
+	schemes  []string
+}
+
+// Discovery is a dns based discovery, which resolves the service name on an interval.
+// The service name is either a SRV name like _grpc._tcp.example.com,
+// or a host with port like example.com:9000, which is resolved by the A/AAAA records.
+// Every resolved address is an instance, whose ID is the address,
+// with an endpoint of each scheme.
+// The scheme of a SRV name is its service label unless WithSchemes is set,
+// the scheme of a host with port must be set by WithSchemes.
+type Discovery struct {
+	opts options
+}
+
+// New creates a dns based discovery.
+func New(opts ...Option) *Discovery {
+	o := options{
+		interval: 30 * time.Second,
+		resolver: net.DefaultResolver,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Discovery{opts: o}
+}
+
+// GetService return the service instances resolved by the service name.
+func (d *Discovery) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	schemes, err := d.schemes(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := d.lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*registry.ServiceInstance, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints := make([]string, 0, len(schemes))
+		for _, scheme := range schemes {
+			endpoints = append(endpoints, scheme+"://"+addr)
+		}
+		services = append(services, &registry.ServiceInstance{
+			ID:        addr,
+			Name:      name,
+			Endpoints: endpoints,
+		})
+	}
+	return services, nil
+}
+
+// Watch creates a watcher according to the service name.
+func (d *Discovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	w := &watcher{
+		d:      d,
+		name:   name,
+		ticker: time.NewTicker(d.opts.interval),
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return w, nil
+}
+
+// schemes returns the schemes of the endpoints of the service name.
+func (d *Discovery) schemes(name string) ([]string, error) {
+	if len(d.opts.schemes) > 0 {
+		return d.opts.schemes, nil
+	}
+	if strings.HasPrefix(name, "_") {
+		// the service label of the SRV name, e.g. _grpc._tcp.example.com
+		if i := strings.IndexByte(name, '.'); i > 1 {
+			return []string{name[1:i]}, nil
+		}
+	}
+	return nil, fmt.Errorf("dns: unknown scheme of %s, which is set by WithSchemes", name)
+}
+
+// lookup returns the sorted addresses of the service name.
+func (d *Discovery) lookup(ctx context.Context, name string) ([]string, error) {
+	var addrs []string
+	if strings.HasPrefix(name, "_") {
+		_, srvs, err := d.opts.resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range srvs {
+			addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+		}
+	} else {
+		host, port, err := net.SplitHostPort(name)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := d.opts.resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+type watcher struct {
+	d      *Discovery
+	name   string
+	ticker *time.Ticker
+	// the addresses last returned, nil before the first return
+	last []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Next returns the services once the resolved addresses changed.
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	first := true
+	for {
+		if !first || w.last != nil {
+			select {
+			case <-w.ctx.Done():
+				return nil, w.ctx.Err()
+			case <-w.ticker.C:
+			}
+		}
+		first = false
+		services, err := w.d.GetService(w.ctx, w.name)
+		if err != nil {
+			if w.ctx.Err() != nil {
+				return nil, w.ctx.Err()
+			}
+			return nil, err
+		}
+		addrs := make([]string, 0, len(services))
+		for _, s := range services {
+			addrs = append(addrs, s.ID)
+		}
+		if w.last == nil && len(addrs) == 0 || equal(w.last, addrs) {
+			continue
+		}
+		w.last = addrs
+		return services, nil
+	}
+}
+
+// Stop close the watcher.
+func (w *watcher) Stop() error {
+	w.cancel()
+	w.ticker.Stop()
+	return nil
+}
+
+func equal(a, b []string) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
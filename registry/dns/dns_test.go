@@ -0,0 +1,78 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDiscovery_GetService(t *testing.T) {
+	d := New(WithSchemes("grpc"))
+	services, err := d.GetService(context.Background(), "localhost:9000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) == 0 {
+		t.Fatalf("expect services, got %v", services)
+	}
+	for _, s := range services {
+		if len(s.Endpoints) != 1 || s.Endpoints[0] != "grpc://"+s.ID {
+			t.Errorf("expect %v, got %v", "grpc://"+s.ID, s.Endpoints)
+		}
+	}
+	if _, err = d.GetService(context.Background(), "localhost"); err == nil {
+		t.Errorf("expect error without port, got %v", err)
+	}
+	if _, err = New().GetService(context.Background(), "localhost:9000"); err == nil {
+		t.Errorf("expect error without scheme, got %v", err)
+	}
+}
+
+func TestDiscovery_Schemes(t *testing.T) {
+	d := New()
+	schemes, err := d.schemes("_grpc._tcp.example.com")
+	if err != nil || len(schemes) != 1 || schemes[0] != "grpc" {
+		t.Errorf("expect %v, got %v %v", []string{"grpc"}, schemes, err)
+	}
+	if _, err = d.schemes("example.com:9000"); err == nil {
+		t.Errorf("expect error, got %v", err)
+	}
+	d = New(WithSchemes("http"))
+	schemes, err = d.schemes("_grpc._tcp.example.com")
+	if err != nil || len(schemes) != 1 || schemes[0] != "http" {
+		t.Errorf("expect %v, got %v %v", []string{"http"}, schemes, err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	d := New(WithInterval(10*time.Millisecond), WithSchemes("grpc"))
+	w, err := d.Watch(context.Background(), "localhost:9000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) == 0 {
+		t.Fatalf("expect services, got %v", services)
+	}
+	// no update until the addresses change
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		t.Fatalf("expect Next to block, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err = w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expect %v, got %v", context.Canceled, err)
+	}
+}
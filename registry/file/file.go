@@ -0,0 +1,251 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var _ registry.Discovery = (*Discovery)(nil)
+
+// Discovery is a file based discovery, the file maps the service names
+// to their instances, in YAML or JSON by the file extension:
+//
+//	helloworld:
+//	  - id: helloworld-1
+//	    version: v1.0.0
+//	    endpoints:
+//	      - grpc://127.0.0.1:9000
+//	      - http://127.0.0.1:8000
+//
+// The name of an instance defaults to the service name.
+// The file is reloaded when it changes, or when the symlinks it resolves through
+// are swapped, e.g. the ..data symlink of a Kubernetes ConfigMap volume.
+type Discovery struct {
+	path string
+	// the resolved path of the file, only accessed by the watching goroutine
+	real string
+	fw   *fsnotify.Watcher
+
+	lock     sync.RWMutex
+	services map[string][]*registry.ServiceInstance
+	watchers map[string]map[*watcher]struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a file based discovery.
+func New(path string) (*Discovery, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	services, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// watch the directory, as editors and config maps replace the file by renaming
+	if err = fw.Add(filepath.Dir(path)); err != nil {
+		_ = fw.Close()
+		return nil, err
+	}
+	real, _ := filepath.EvalSymlinks(path)
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Discovery{
+		path:     path,
+		real:     real,
+		fw:       fw,
+		services: services,
+		watchers: make(map[string]map[*watcher]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go d.watch()
+	return d, nil
+}
+
+// Close stops watching the file.
+func (d *Discovery) Close() error {
+	d.cancel()
+	return d.fw.Close()
+}
+
+// GetService return the service instances in memory according to the service name.
+func (d *Discovery) GetService(_ context.Context, name string) ([]*registry.ServiceInstance, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.snapshot(name), nil
+}
+
+// Watch creates a watcher according to the service name.
+func (d *Discovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	w := &watcher{
+		name:  name,
+		d:     d,
+		event: make(chan struct{}, 1),
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	ws, ok := d.watchers[name]
+	if !ok {
+		ws = make(map[*watcher]struct{})
+		d.watchers[name] = ws
+	}
+	ws[w] = struct{}{}
+	// the initial snapshot
+	if len(d.services[name]) > 0 {
+		w.event <- struct{}{}
+	}
+	return w, nil
+}
+
+func (d *Discovery) watch() {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case event, ok := <-d.fw.Events:
+			if !ok {
+				return
+			}
+			if d.changed(event) {
+				d.reload()
+			}
+		case err, ok := <-d.fw.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("[registry/file] failed to watch %s: %v", d.path, err)
+		}
+	}
+}
+
+// changed reports whether the event changes the file, which is either written or
+// replaced, or resolved to another file as the symlinks in the directory are swapped.
+func (d *Discovery) changed(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+		return false
+	}
+	real, err := filepath.EvalSymlinks(d.path)
+	if err != nil {
+		// the file is removed, the services are kept until it's back
+		return false
+	}
+	if filepath.Clean(event.Name) == d.path && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+		d.real = real
+		return true
+	}
+	if real == d.real {
+		return false
+	}
+	d.real = real
+	return true
+}
+
+func (d *Discovery) reload() {
+	services, err := load(d.path)
+	if err != nil {
+		// keep the last services, the file may be in the middle of writing
+		log.Errorf("[registry/file] failed to reload %s: %v", d.path, err)
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	old := d.services
+	d.services = services
+	for name, ws := range d.watchers {
+		if reflect.DeepEqual(old[name], services[name]) {
+			continue
+		}
+		for w := range ws {
+			select {
+			case w.event <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (d *Discovery) snapshot(name string) []*registry.ServiceInstance {
+	ss := d.services[name]
+	services := make([]*registry.ServiceInstance, 0, len(ss))
+	return append(services, ss...)
+}
+
+func load(path string) (map[string][]*registry.ServiceInstance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	services := make(map[string][]*registry.ServiceInstance)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &services)
+	case ".json":
+		err = json.Unmarshal(data, &services)
+	default:
+		err = fmt.Errorf("unsupported file format: %s", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for name, ss := range services {
+		for _, s := range ss {
+			if s.Name == "" {
+				s.Name = name
+			}
+		}
+	}
+	return services, nil
+}
+
+type watcher struct {
+	name  string
+	d     *Discovery
+	event chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Next returns the latest services of the watched name once they changed,
+// it returns the context error after the watcher is stopped.
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case <-w.event:
+	}
+	w.d.lock.RLock()
+	defer w.d.lock.RUnlock()
+	return w.d.snapshot(w.name), nil
+}
+
+// Stop close the watcher.
+func (w *watcher) Stop() error {
+	w.cancel()
+	w.d.lock.Lock()
+	defer w.d.lock.Unlock()
+	delete(w.d.watchers[w.name], w)
+	if len(w.d.watchers[w.name]) == 0 {
+		delete(w.d.watchers, w.name)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+const (
+	_testYAML = `
+helloworld:
+  - id: "1"
+    version: v1.0.0
+    metadata:
+      weight: "10"
+    endpoints:
+      - grpc://127.0.0.1:9000
+`
+	_testJSON = `{"helloworld":[
+	{"id":"1","endpoints":["grpc://127.0.0.1:9000"]},
+	{"id":"2","endpoints":["grpc://127.0.0.1:9001"]}
+]}`
+)
+
+func TestDiscovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	if err := os.WriteFile(path, []byte(_testYAML), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	d, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	services, err := d.GetService(context.Background(), "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("expect %v, got %v", 1, len(services))
+	}
+	s := services[0]
+	if s.Name != "helloworld" || s.Version != "v1.0.0" || s.Metadata["weight"] != "10" || s.Endpoints[0] != "grpc://127.0.0.1:9000" {
+		t.Errorf("unexpected instance %+v", s)
+	}
+
+	w, err := d.Watch(context.Background(), "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the initial snapshot
+	if services, err = w.Next(); err != nil || len(services) != 1 {
+		t.Fatalf("expect %v, got %v %v", 1, len(services), err)
+	}
+	// replace the file like the editors do
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, []byte(`
+helloworld:
+  - id: "1"
+  - id: "2"
+`), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	if services, err = next(w.Next); err != nil || len(services) != 2 {
+		t.Fatalf("expect %v, got %v %v", 2, len(services), err)
+	}
+	if err = w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Next(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expect %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestDiscovery_ConfigMap(t *testing.T) {
+	// the layout of a config map volume: registry.yaml -> ..data/registry.yaml, ..data -> ..v1
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "..v1"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "..v1", "registry.yaml"), []byte(_testYAML), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "registry.yaml")
+	if err := os.Symlink(filepath.Join("..data", "registry.yaml"), path); err != nil {
+		t.Fatal(err)
+	}
+	d, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	w, err := d.Watch(context.Background(), "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if services, err := w.Next(); err != nil || len(services) != 1 {
+		t.Fatalf("expect %v, got %v %v", 1, len(services), err)
+	}
+	// the update swaps the ..data symlink atomically
+	if err = os.Mkdir(filepath.Join(dir, "..v2"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "..v2", "registry.yaml"), []byte(_testJSON), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if services, err := next(w.Next); err != nil || len(services) != 2 {
+		t.Fatalf("expect %v, got %v %v", 2, len(services), err)
+	}
+}
+
+func TestDiscovery_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+	if err := os.WriteFile(path, []byte(_testJSON), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	d, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	services, err := d.GetService(context.Background(), "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 {
+		t.Errorf("expect %v, got %v", 2, len(services))
+	}
+}
+
+func TestNew_Error(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "registry.yaml")); err == nil {
+		t.Errorf("expect error of missing file, got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "registry.txt")
+	if err := os.WriteFile(path, []byte(_testJSON), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(path); err == nil {
+		t.Errorf("expect error of unsupported format, got %v", err)
+	}
+}
+
+func next(f func() ([]*registry.ServiceInstance, error)) ([]*registry.ServiceInstance, error) {
+	type result struct {
+		services []*registry.ServiceInstance
+		err      error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		services, err := f()
+		ch <- result{services, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.services, r.err
+	case <-time.After(3 * time.Second):
+		return nil, errors.New("timeout")
+	}
+}
@@ -0,0 +1,220 @@
+package registry
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// KeepAliveOption is keepalive option.
+type KeepAliveOption func(*keepAliveOptions)
+
+// WithKeepAliveInterval with the interval of checking the registration.
+func WithKeepAliveInterval(d time.Duration) KeepAliveOption {
+	return func(o *keepAliveOptions) {
+		o.interval = d
+	}
+}
+
+// WithKeepAliveTimeout with the timeout of a check or a registration.
+func WithKeepAliveTimeout(d time.Duration) KeepAliveOption {
+	return func(o *keepAliveOptions) {
+		o.timeout = d
+	}
+}
+
+// WithKeepAliveBackoff with the exponential backoff of re-registration on failure.
+func WithKeepAliveBackoff(base, max time.Duration) KeepAliveOption {
+	return func(o *keepAliveOptions) {
+		o.baseBackoff = base
+		o.maxBackoff = max
+	}
+}
+
+// WithOnUnregistered with the callback once a lost registration is restored,
+// with the time spent unregistered, e.g. to observe a metric.
+func WithOnUnregistered(f func(service *ServiceInstance, d time.Duration)) KeepAliveOption {
+	return func(o *keepAliveOptions) {
+		o.onUnregistered = f
+	}
+}
+
+type keepAliveOptions struct {
+	interval       time.Duration
+	timeout        time.Duration
+	baseBackoff    time.Duration
+	maxBackoff     time.Duration
+	onUnregistered func(service *ServiceInstance, d time.Duration)
+}
+
+type keepAlive struct {
+	Registrar
+	checker Checker
+	opts    keepAliveOptions
+
+	mu    sync.Mutex
+	loops map[string]*loop
+}
+
+type loop struct {
+	service *ServiceInstance
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// KeepAlive wraps the registrar, which checks the registration periodically after
+// the first registration succeeds, and registers the instance again only if it's gone,
+// with backoff on failure, until the instance is deregistered.
+// The registration is kept alive only if the registrar implements Checker,
+// otherwise the registrar is used as is.
+func KeepAlive(r Registrar, opts ...KeepAliveOption) Registrar {
+	o := keepAliveOptions{
+		interval:    30 * time.Second,
+		timeout:     10 * time.Second,
+		baseBackoff: time.Second,
+		maxBackoff:  30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	checker, _ := r.(Checker)
+	return &keepAlive{
+		Registrar: r,
+		checker:   checker,
+		opts:      o,
+		loops:     make(map[string]*loop),
+	}
+}
+
+// Register the registration, and keeps it alive.
+func (k *keepAlive) Register(ctx context.Context, service *ServiceInstance) error {
+	// the old loop is stopped first, so it never registers the stale instance after this one
+	old := k.stop(service.ID)
+	if err := k.Registrar.Register(ctx, service); err != nil {
+		if old != nil {
+			// keep the previous registration alive
+			k.start(old.service)
+		}
+		return err
+	}
+	k.start(service)
+	return nil
+}
+
+// start starts the loop which keeps the registration alive.
+func (k *keepAlive) start(service *ServiceInstance) {
+	if k.checker == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &loop{service: service, cancel: cancel, done: make(chan struct{})}
+	k.mu.Lock()
+	old, ok := k.loops[service.ID]
+	k.loops[service.ID] = l
+	k.mu.Unlock()
+	if ok {
+		old.cancel()
+		<-old.done
+	}
+	go k.keepAlive(ctx, l, service)
+}
+
+// stop stops the loop of the instance, and waits for it to return.
+func (k *keepAlive) stop(id string) *loop {
+	k.mu.Lock()
+	l, ok := k.loops[id]
+	delete(k.loops, id)
+	k.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	l.cancel()
+	<-l.done
+	return l
+}
+
+// Deregister stops keeping the registration alive, and deregisters it.
+func (k *keepAlive) Deregister(ctx context.Context, service *ServiceInstance) error {
+	k.stop(service.ID)
+	return k.Registrar.Deregister(ctx, service)
+}
+
+func (k *keepAlive) keepAlive(ctx context.Context, l *loop, service *ServiceInstance) {
+	defer close(l.done)
+	var (
+		since   time.Time
+		retries int
+	)
+	timer := time.NewTimer(k.opts.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		ok, err := k.check(ctx, service)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warnf("[registry] failed to check instance %s: %v", service.ID, err)
+			timer.Reset(k.backoff(retries))
+			retries++
+			continue
+		}
+		if !ok {
+			if since.IsZero() {
+				since = time.Now()
+				log.Warnf("[registry] instance %s is unregistered", service.ID)
+			}
+			rctx, cancel := context.WithTimeout(ctx, k.opts.timeout)
+			err = k.Registrar.Register(rctx, service)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Warnf("[registry] failed to register instance %s: %v", service.ID, err)
+				timer.Reset(k.backoff(retries))
+				retries++
+				continue
+			}
+		}
+		retries = 0
+		if !since.IsZero() {
+			d := time.Since(since)
+			log.Infof("[registry] instance %s is registered again after %s", service.ID, d)
+			if k.opts.onUnregistered != nil {
+				k.opts.onUnregistered(service, d)
+			}
+			since = time.Time{}
+		}
+		timer.Reset(k.opts.interval)
+	}
+}
+
+// check reports whether the registration is alive.
+func (k *keepAlive) check(ctx context.Context, service *ServiceInstance) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, k.opts.timeout)
+	defer cancel()
+	return k.checker.Registered(ctx, service)
+}
+
+// backoff returns the exponential backoff with full jitter.
+func (k *keepAlive) backoff(retries int) time.Duration {
+	d := k.opts.baseBackoff
+	for i := 0; i < retries && d < k.opts.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > k.opts.maxBackoff {
+		d = k.opts.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
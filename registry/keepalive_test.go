@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type mockRegistrar struct {
+	fail         int32
+	lost         int32
+	registers    int32
+	deregistered int32
+}
+
+func (r *mockRegistrar) Register(context.Context, *ServiceInstance) error {
+	atomic.AddInt32(&r.registers, 1)
+	if atomic.LoadInt32(&r.fail) == 1 {
+		return errors.New("session expired")
+	}
+	atomic.StoreInt32(&r.lost, 0)
+	return nil
+}
+
+func (r *mockRegistrar) Registered(context.Context, *ServiceInstance) (bool, error) {
+	return atomic.LoadInt32(&r.lost) == 0, nil
+}
+
+func (r *mockRegistrar) Deregister(context.Context, *ServiceInstance) error {
+	atomic.StoreInt32(&r.deregistered, 1)
+	return nil
+}
+
+func TestKeepAlive(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		unregistered time.Duration
+	)
+	r := &mockRegistrar{}
+	k := KeepAlive(r,
+		WithKeepAliveInterval(10*time.Millisecond),
+		WithKeepAliveBackoff(time.Millisecond, 5*time.Millisecond),
+		WithOnUnregistered(func(_ *ServiceInstance, d time.Duration) {
+			mu.Lock()
+			unregistered = d
+			mu.Unlock()
+		}),
+	)
+	service := &ServiceInstance{ID: "1", Name: "helloworld"}
+	if err := k.Register(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&r.registers); n != 1 {
+		t.Errorf("expect no re-registration while registered, got %v", n)
+	}
+
+	atomic.StoreInt32(&r.fail, 1)
+	atomic.StoreInt32(&r.lost, 1)
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&r.registers); n < 2 {
+		t.Errorf("expect re-registrations once lost, got %v", n)
+	}
+	atomic.StoreInt32(&r.fail, 0)
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&r.lost) != 0 {
+		t.Errorf("expect registered again")
+	}
+	mu.Lock()
+	if unregistered <= 0 {
+		t.Errorf("expect the unregistered time, got %v", unregistered)
+	}
+	mu.Unlock()
+
+	if err := k.Deregister(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&r.deregistered) != 1 {
+		t.Errorf("expect deregistered")
+	}
+	n := atomic.LoadInt32(&r.registers)
+	time.Sleep(30 * time.Millisecond)
+	if m := atomic.LoadInt32(&r.registers); m != n {
+		t.Errorf("expect no registration after deregistered, got %v", m-n)
+	}
+}
+
+func TestKeepAlive_RegisterError(t *testing.T) {
+	r := &mockRegistrar{fail: 1}
+	k := KeepAlive(r, WithKeepAliveInterval(10*time.Millisecond))
+	if err := k.Register(context.Background(), &ServiceInstance{ID: "1"}); err == nil {
+		t.Fatal("expect error")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := atomic.LoadInt32(&r.registers); n != 1 {
+		t.Errorf("expect %v, got %v", 1, n)
+	}
+}
+
+type versionRegistrar struct {
+	mu       sync.Mutex
+	versions []string
+}
+
+func (r *versionRegistrar) Register(_ context.Context, service *ServiceInstance) error {
+	r.mu.Lock()
+	r.versions = append(r.versions, service.Version)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *versionRegistrar) Deregister(context.Context, *ServiceInstance) error { return nil }
+
+// Registered reports the registration is lost, so that it's always registered again.
+func (r *versionRegistrar) Registered(context.Context, *ServiceInstance) (bool, error) {
+	return false, nil
+}
+
+type plainRegistrar struct {
+	registers int32
+}
+
+func (r *plainRegistrar) Register(context.Context, *ServiceInstance) error {
+	atomic.AddInt32(&r.registers, 1)
+	return nil
+}
+
+func (r *plainRegistrar) Deregister(context.Context, *ServiceInstance) error { return nil }
+
+func TestKeepAlive_NoChecker(t *testing.T) {
+	r := &plainRegistrar{}
+	k := KeepAlive(r, WithKeepAliveInterval(time.Millisecond))
+	service := &ServiceInstance{ID: "1"}
+	if err := k.Register(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := k.Deregister(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&r.registers); n != 1 {
+		t.Errorf("expect %v, got %v", 1, n)
+	}
+}
+
+func TestKeepAlive_Reregister(t *testing.T) {
+	r := &versionRegistrar{}
+	k := KeepAlive(r, WithKeepAliveInterval(time.Millisecond))
+	if err := k.Register(context.Background(), &ServiceInstance{ID: "1", Version: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	service := &ServiceInstance{ID: "1", Version: "v2"}
+	if err := k.Register(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	n := len(r.versions)
+	r.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	if err := k.Deregister(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range r.versions[n-1:] {
+		if v != "v2" {
+			t.Fatalf("expect no stale registration after re-registered, got %v", r.versions[n-1:])
+		}
+	}
+}
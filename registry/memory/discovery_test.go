@@ -0,0 +1,92 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	pb "github.com/go-kratos/kratos/v2/internal/testdata/helloworld"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+	"github.com/go-kratos/kratos/v2/transport/grpc"
+	"github.com/go-kratos/kratos/v2/transport/http"
+)
+
+type server struct {
+	pb.UnimplementedGreeterServer
+	name string
+}
+
+func (s *server) SayHello(_ context.Context, in *pb.HelloRequest) (*pb.HelloReply, error) {
+	return &pb.HelloReply{Message: fmt.Sprintf("Hello %s from %s", in.Name, s.name)}, nil
+}
+
+type endpointer interface {
+	Endpoint() (*url.URL, error)
+	Start(context.Context) error
+	Stop(context.Context) error
+}
+
+func register(t *testing.T, r registry.Registrar, id string, srv endpointer) {
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	t.Cleanup(func() { _ = srv.Stop(context.Background()) })
+	u, err := srv.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Register(context.Background(), &registry.ServiceInstance{
+		ID:        id,
+		Name:      "helloworld",
+		Endpoints: []string{u.String()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGRPCDiscovery(t *testing.T) {
+	r := memory.New()
+	srv := grpc.NewServer(grpc.Address("127.0.0.1:0"))
+	pb.RegisterGreeterServer(srv, &server{name: "grpc"})
+	register(t, r, "grpc", srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialInsecure(ctx, grpc.WithEndpoint("discovery:///helloworld"), grpc.WithDiscovery(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reply, err := pb.NewGreeterClient(conn).SayHello(ctx, &pb.HelloRequest{Name: "kratos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Message != "Hello kratos from grpc" {
+		t.Errorf("expect %v, got %v", "Hello kratos from grpc", reply.Message)
+	}
+}
+
+func TestHTTPDiscovery(t *testing.T) {
+	r := memory.New()
+	srv := http.NewServer(http.Address("127.0.0.1:0"))
+	pb.RegisterGreeterHTTPServer(srv, &server{name: "http"})
+	register(t, r, "http", srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := http.NewClient(ctx, http.WithEndpoint("discovery:///helloworld"), http.WithDiscovery(r), http.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	reply, err := pb.NewGreeterHTTPClient(client).SayHello(ctx, &pb.HelloRequest{Name: "kratos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Message != "Hello kratos from http" {
+		t.Errorf("expect %v, got %v", "Hello kratos from http", reply.Message)
+	}
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var (
+	_ registry.Registrar = &Registry{}
+	_ registry.Checker   = &Registry{}
+	_ registry.Discovery = &Registry{}
+)
+
+// Registry is an in-memory registry, which is useful for tests
+// and the services deployed in a single binary.
+type Registry struct {
+	lock     sync.RWMutex
+	services map[string][]*registry.ServiceInstance
+	watchers map[string]map[*watcher]struct{}
+}
+
+// New creates an in-memory registry.
+func New() *Registry {
+	return &Registry{
+		services: make(map[string][]*registry.ServiceInstance),
+		watchers: make(map[string]map[*watcher]struct{}),
+	}
+}
+
+// Register the registration, an instance with the same ID is replaced.
+func (r *Registry) Register(_ context.Context, service *registry.ServiceInstance) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ss := r.services[service.Name]
+	for i, s := range ss {
+		if s.ID == service.ID {
+			ss = append(ss[:i:i], ss[i+1:]...)
+			break
+		}
+	}
+	r.services[service.Name] = append(ss, service)
+	r.notify(service.Name)
+	return nil
+}
+
+// Deregister the registration.
+func (r *Registry) Deregister(_ context.Context, service *registry.ServiceInstance) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ss := r.services[service.Name]
+	for i, s := range ss {
+		if s.ID == service.ID {
+			ss = append(ss[:i:i], ss[i+1:]...)
+			if len(ss) == 0 {
+				delete(r.services, service.Name)
+			} else {
+				r.services[service.Name] = ss
+			}
+			r.notify(service.Name)
+			break
+		}
+	}
+	return nil
+}
+
+// Registered reports whether the instance is registered.
+func (r *Registry) Registered(_ context.Context, service *registry.ServiceInstance) (bool, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, s := range r.services[service.Name] {
+		if s.ID == service.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetService return the service instances in memory according to the service name.
+func (r *Registry) GetService(_ context.Context, name string) ([]*registry.ServiceInstance, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.snapshot(name), nil
+}
+
+// Watch creates a watcher according to the service name.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	w := &watcher{
+		name:  name,
+		r:     r,
+		event: make(chan struct{}, 1),
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	ws, ok := r.watchers[name]
+	if !ok {
+		ws = make(map[*watcher]struct{})
+		r.watchers[name] = ws
+	}
+	ws[w] = struct{}{}
+	// the initial snapshot
+	if len(r.services[name]) > 0 {
+		w.event <- struct{}{}
+	}
+	return w, nil
+}
+
+func (r *Registry) snapshot(name string) []*registry.ServiceInstance {
+	ss := r.services[name]
+	services := make([]*registry.ServiceInstance, 0, len(ss))
+	return append(services, ss...)
+}
+
+// notify must be called with the lock held.
+func (r *Registry) notify(name string) {
+	for w := range r.watchers[name] {
+		select {
+		case w.event <- struct{}{}:
+		default:
+			// the watcher has a pending event, which reads the latest services
+		}
+	}
+}
+
+type watcher struct {
+	name  string
+	r     *Registry
+	event chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Next returns the latest services of the watched name once they changed,
+// it returns the context error after the watcher is stopped.
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case <-w.event:
+	}
+	w.r.lock.RLock()
+	defer w.r.lock.RUnlock()
+	return w.r.snapshot(w.name), nil
+}
+
+// Stop close the watcher.
+func (w *watcher) Stop() error {
+	w.cancel()
+	w.r.lock.Lock()
+	defer w.r.lock.Unlock()
+	delete(w.r.watchers[w.name], w)
+	if len(w.r.watchers[w.name]) == 0 {
+		delete(w.r.watchers, w.name)
+	}
+	return nil
+}
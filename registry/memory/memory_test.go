@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := New()
+	s1 := &registry.ServiceInstance{ID: "1", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000"}}
+	s2 := &registry.ServiceInstance{ID: "2", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9001"}}
+	if err := r.Register(ctx, s1); err != nil {
+		t.Fatal(err)
+	}
+	// register the same instance again
+	if err := r.Register(ctx, s1); err != nil {
+		t.Fatal(err)
+	}
+	services, err := r.GetService(ctx, "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 {
+		t.Errorf("expect %v, got %v", 1, len(services))
+	}
+
+	w, err := r.Watch(ctx, "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the initial snapshot
+	services, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 {
+		t.Errorf("expect %v, got %v", 1, len(services))
+	}
+
+	if err = r.Register(ctx, s2); err != nil {
+		t.Fatal(err)
+	}
+	if services, err = w.Next(); err != nil || len(services) != 2 {
+		t.Errorf("expect %v, got %v %v", 2, len(services), err)
+	}
+	if err = r.Deregister(ctx, s1); err != nil {
+		t.Fatal(err)
+	}
+	if services, err = w.Next(); err != nil || len(services) != 1 || services[0].ID != "2" {
+		t.Errorf("expect %v, got %v %v", s2, services, err)
+	}
+	if ok, _ := r.Registered(ctx, s1); ok {
+		t.Errorf("expect %v, got %v", false, ok)
+	}
+	if ok, _ := r.Registered(ctx, s2); !ok {
+		t.Errorf("expect %v, got %v", true, ok)
+	}
+	if err = r.Deregister(ctx, s2); err != nil {
+		t.Fatal(err)
+	}
+	if services, err = w.Next(); err != nil || len(services) != 0 {
+		t.Errorf("expect %v, got %v %v", 0, len(services), err)
+	}
+	if err = w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.watchers) != 0 {
+		t.Errorf("expect %v, got %v", 0, len(r.watchers))
+	}
+}
+
+func TestWatcher_Stop(t *testing.T) {
+	r := New()
+	// no initial snapshot without instances
+	w, err := r.Watch(context.Background(), "helloworld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err = w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expect %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect Next to be unblocked")
+	}
+}
@@ -2,6 +2,16 @@ package registry
 
 import "context"
 
+const (
+	// WeightKey is the metadata key of the instance weight.
+	WeightKey = "weight"
+	// StatusKey is the metadata key of the instance status.
+	StatusKey = "status"
+	// StatusDraining is the status of an instance which is going to stop,
+	// the selectors send no new traffic to it unless all instances are draining.
+	StatusDraining = "draining"
+)
+
 // Registrar is service registrar.
 type Registrar interface {
 	// Register the registration.
@@ -10,6 +20,13 @@ type Registrar interface {
 	Deregister(ctx context.Context, service *ServiceInstance) error
 }
 
+// Checker is an optional interface of the registrar, which checks if the registration
+// is still alive, e.g. it's lost after the registry session expired.
+type Checker interface {
+	// Registered reports whether the registration is alive in the registry.
+	Registered(ctx context.Context, service *ServiceInstance) (bool, error)
+}
+
 // Discovery is service discovery.
 type Discovery interface {
 	// GetService return the service instances in memory according to the service name.
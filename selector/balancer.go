@@ -5,7 +5,8 @@ import (
 	"time"
 )
 
-// Balancer is balancer interface
+// Balancer is balancer interface, it's notified of the node changes
+// if it implements the Rebalancer as well.
 type Balancer interface {
 	Pick(ctx context.Context, nodes []WeightedNode) (selected WeightedNode, done DoneFunc, err error)
 }
@@ -36,3 +37,13 @@ type WeightedNode interface {
 type WeightedNodeBuilder interface {
 	Build(Node) WeightedNode
 }
+
+// ScopedNodeBuilder is a WeightedNodeBuilder which keeps the state of the nodes,
+// the DefaultBuilder creates a new one for each selector by New,
+// so that the state is never shared by the selectors of different services.
+type ScopedNodeBuilder interface {
+	WeightedNodeBuilder
+
+	// New creates a node builder with the same options and a clean state.
+	New() WeightedNodeBuilder
+}
@@ -0,0 +1,233 @@
+package chash
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/node/direct"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+const (
+	// Name is consistent hash balancer name
+	Name = "chash"
+
+	// the number of virtual nodes for a node with the default weight
+	defaultReplicas = 160
+	defaultWeight   = 100
+)
+
+var _ selector.Balancer = &Balancer{}
+
+// KeyFunc returns the hash key of the request.
+type KeyFunc func(ctx context.Context) string
+
+// WithFilter with select filters
+func WithFilter(filters ...selector.Filter) Option {
+	return func(o *options) {
+		o.filters = filters
+	}
+}
+
+// WithKey with a custom hash key func.
+func WithKey(fn KeyFunc) Option {
+	return func(o *options) {
+		o.key = fn
+	}
+}
+
+// WithMetadataKey uses the client metadata value of key as the hash key.
+func WithMetadataKey(key string) Option {
+	return WithKey(func(ctx context.Context) string {
+		if md, ok := metadata.FromClientContext(ctx); ok {
+			return md.Get(key)
+		}
+		return ""
+	})
+}
+
+// WithHeaderKey uses the client request header value of key as the hash key.
+func WithHeaderKey(key string) Option {
+	return WithKey(func(ctx context.Context) string {
+		if tr, ok := transport.FromClientContext(ctx); ok {
+			return tr.RequestHeader().Get(key)
+		}
+		return ""
+	})
+}
+
+// WithReplicas with the number of virtual nodes for a node with the default weight.
+func WithReplicas(n int) Option {
+	return func(o *options) {
+		o.replicas = n
+	}
+}
+
+// Option is consistent hash builder option.
+type Option func(o *options)
+
+// options is consistent hash builder options
+type options struct {
+	filters  []selector.Filter
+	key      KeyFunc
+	replicas int
+}
+
+// New creates a consistent hash selector.
+func New(opts ...Option) selector.Selector {
+	return NewBuilder(opts...).Build()
+}
+
+// Balancer is a consistent hash balancer, the requests with the same key
+// are sent to the same node, and only the keys of the changed nodes move
+// when the nodes change. The requests without a key are picked randomly.
+// The ring is built when the nodes are applied, the nodes removed by the
+// filters are skipped while walking the ring, so only their keys move.
+type Balancer struct {
+	key      KeyFunc
+	replicas int
+
+	mu   sync.RWMutex
+	ring *ring
+}
+
+// Apply rebuilds the ring of the nodes.
+func (b *Balancer) Apply(nodes []selector.Node) {
+	r := newRing(nodes, b.replicas)
+	b.mu.Lock()
+	b.ring = r
+	b.mu.Unlock()
+}
+
+// Pick is pick a weighted node.
+func (b *Balancer) Pick(ctx context.Context, nodes []selector.WeightedNode) (selector.WeightedNode, selector.DoneFunc, error) {
+	if len(nodes) == 0 {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	var key string
+	if b.key != nil {
+		key = b.key(ctx)
+	}
+	var selected selector.WeightedNode
+	if key == "" {
+		selected = nodes[rand.Intn(len(nodes))]
+	} else {
+		selected = b.get(key, nodes)
+	}
+	d := selected.Pick()
+	return selected, d, nil
+}
+
+// get returns the first candidate clockwise from the hash of key.
+func (b *Balancer) get(key string, nodes []selector.WeightedNode) selector.WeightedNode {
+	candidates := make(map[string]selector.WeightedNode, len(nodes))
+	for _, n := range nodes {
+		candidates[n.Address()] = n
+	}
+	b.mu.RLock()
+	r := b.ring
+	b.mu.RUnlock()
+	if r != nil {
+		if n := r.get(key, candidates); n != nil {
+			return n
+		}
+	}
+	// the candidates are not in the ring as they are not applied
+	raw := make([]selector.Node, len(nodes))
+	for i, n := range nodes {
+		raw[i] = n
+	}
+	return newRing(raw, b.replicas).get(key, candidates)
+}
+
+type ring struct {
+	hashes []uint64
+	addrs  map[uint64]string
+}
+
+func newRing(nodes []selector.Node, replicas int) *ring {
+	r := &ring{addrs: make(map[uint64]string)}
+	for _, n := range nodes {
+		weight := int64(defaultWeight)
+		if w := n.InitialWeight(); w != nil && *w > 0 {
+			weight = *w
+		}
+		vnodes := int(int64(replicas) * weight / defaultWeight)
+		if vnodes < 1 {
+			vnodes = 1
+		}
+		for i := 0; i < vnodes; i++ {
+			h := hash(n.Address() + "#" + strconv.Itoa(i))
+			if prev, ok := r.addrs[h]; ok && prev < n.Address() {
+				// keep the collision deterministic regardless of the nodes order
+				continue
+			}
+			if _, ok := r.addrs[h]; !ok {
+				r.hashes = append(r.hashes, h)
+			}
+			r.addrs[h] = n.Address()
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// get returns the first candidate clockwise from the hash of key,
+// nil is returned if none of the candidates is in the ring.
+func (r *ring) get(key string, candidates map[string]selector.WeightedNode) selector.WeightedNode {
+	h := hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	for j := 0; j < len(r.hashes); j++ {
+		if n, ok := candidates[r.addrs[r.hashes[(i+j)%len(r.hashes)]]]; ok {
+			return n
+		}
+	}
+	return nil
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	// fnv alone spreads similar keys poorly, so the sum is mixed by the splitmix64 finalizer
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// NewBuilder returns a selector builder with consistent hash balancer
+func NewBuilder(opts ...Option) selector.Builder {
+	option := options{replicas: defaultReplicas}
+	for _, opt := range opts {
+		opt(&option)
+	}
+	return &selector.DefaultBuilder{
+		Filters:  option.filters,
+		Balancer: &Builder{key: option.key, replicas: option.replicas},
+		Node:     &direct.Builder{},
+	}
+}
+
+// Builder is consistent hash builder
+type Builder struct {
+	key      KeyFunc
+	replicas int
+}
+
+// Build creates Balancer
+func (b *Builder) Build() selector.Balancer {
+	replicas := b.replicas
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+	return &Balancer{key: b.key, replicas: replicas}
+}
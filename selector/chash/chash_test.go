@@ -0,0 +1,128 @@
+package chash
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+const testKey = "x-md-global-key"
+
+func newNodes(weights ...string) []selector.Node {
+	nodes := make([]selector.Node, 0, len(weights))
+	for i, w := range weights {
+		addr := fmt.Sprintf("127.0.0.%d:8080", i+1)
+		nodes = append(nodes, selector.NewNode("http", addr, &registry.ServiceInstance{
+			ID:       addr,
+			Metadata: map[string]string{"weight": w},
+		}))
+	}
+	return nodes
+}
+
+func pick(t *testing.T, s selector.Selector, key string) string {
+	ctx := metadata.NewClientContext(context.Background(), metadata.Metadata{testKey: key})
+	n, done, err := s.Select(ctx)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	done(ctx, selector.DoneInfo{})
+	return n.Address()
+}
+
+func TestAffinity(t *testing.T) {
+	s := New(WithMetadataKey(testKey))
+	s.Apply(newNodes("100", "100", "100"))
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		if a, b := pick(t, s, key), pick(t, s, key); a != b {
+			t.Errorf("expect the same node for key %s, got %s and %s", key, a, b)
+		}
+	}
+}
+
+func TestWeight(t *testing.T) {
+	s := New(WithMetadataKey(testKey))
+	s.Apply(newNodes("100", "300"))
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[pick(t, s, fmt.Sprintf("user-%d", i))]++
+	}
+	if c := counts["127.0.0.2:8080"]; c < 6500 || c > 8500 {
+		t.Errorf("expect about 7500 keys on the heavier node, got %d", c)
+	}
+}
+
+func TestMinimalMovement(t *testing.T) {
+	s := New(WithMetadataKey(testKey))
+	nodes := newNodes("100", "100", "100", "100")
+	s.Apply(nodes)
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		before[key] = pick(t, s, key)
+	}
+	// remove the last node
+	s.Apply(nodes[:3])
+	removed := nodes[3].Address()
+	for key, addr := range before {
+		after := pick(t, s, key)
+		if addr != removed && after != addr {
+			t.Errorf("expect key %s to stay on %s, got %s", key, addr, after)
+		}
+	}
+}
+
+func TestFilteredMovement(t *testing.T) {
+	s := New(WithMetadataKey(testKey))
+	nodes := newNodes("100", "100", "100", "100")
+	s.Apply(nodes)
+	removed := nodes[3].Address()
+	filter := selector.WithFilter(func(_ context.Context, nodes []selector.Node) []selector.Node {
+		filtered := make([]selector.Node, 0, len(nodes))
+		for _, n := range nodes {
+			if n.Address() != removed {
+				filtered = append(filtered, n)
+			}
+		}
+		return filtered
+	})
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		addr := pick(t, s, key)
+		ctx := metadata.NewClientContext(context.Background(), metadata.Metadata{testKey: key})
+		n, done, err := s.Select(ctx, filter)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		done(ctx, selector.DoneInfo{})
+		if n.Address() == removed {
+			t.Fatalf("expect the filtered node %s not to be picked", removed)
+		}
+		if addr != removed && n.Address() != addr {
+			t.Errorf("expect key %s to stay on %s, got %s", key, addr, n.Address())
+		}
+	}
+}
+
+func TestHeaderKey(t *testing.T) {
+	s := New(WithHeaderKey(testKey))
+	s.Apply(newNodes("100", "100"))
+	// no client transport, the node is picked randomly
+	n, _, err := s.Select(context.Background())
+	if err != nil || n == nil {
+		t.Errorf("expect node, got %v %v", n, err)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	b := &Balancer{}
+	_, _, err := b.Pick(context.Background(), []selector.WeightedNode{})
+	if err == nil {
+		t.Errorf("expect no available node error")
+	}
+}
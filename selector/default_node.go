@@ -8,6 +8,7 @@ import (
 
 // DefaultNode is selector node
 type DefaultNode struct {
+	id       string
 	scheme   string
 	addr     string
 	weight   *int64
@@ -16,6 +17,11 @@ type DefaultNode struct {
 	metadata map[string]string
 }
 
+// ID is the id of the service instance of the node
+func (n *DefaultNode) ID() string {
+	return n.id
+}
+
 // Scheme is node scheme
 func (n *DefaultNode) Scheme() string {
 	return n.scheme
@@ -53,10 +59,11 @@ func NewNode(scheme, addr string, ins *registry.ServiceInstance) Node {
 		addr:   addr,
 	}
 	if ins != nil {
+		n.id = ins.ID
 		n.name = ins.Name
 		n.version = ins.Version
 		n.metadata = ins.Metadata
-		if str, ok := ins.Metadata["weight"]; ok {
+		if str, ok := ins.Metadata[registry.WeightKey]; ok {
 			if weight, err := strconv.ParseInt(str, 10, 64); err == nil {
 				n.weight = &weight
 			}
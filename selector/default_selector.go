@@ -3,6 +3,8 @@ package selector
 import (
 	"context"
 	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/registry"
 )
 
 // Default is composite selector.
@@ -27,6 +29,18 @@ func (d *Default) Select(ctx context.Context, opts ...SelectOption) (selected No
 	for _, o := range opts {
 		o(&options)
 	}
+	if ctxOpts, ok := FromSelectOptionsContext(ctx); ok {
+		// each option is applied on its own, so the filters carried by ctx are
+		// appended to the explicit ones instead of replacing them.
+		// the capacity is clipped to avoid writing into the caller's slice.
+		options.Filters = options.Filters[:len(options.Filters):len(options.Filters)]
+		for _, o := range ctxOpts {
+			var co SelectOptions
+			o(&co)
+			options.Filters = append(options.Filters, co.Filters...)
+			options.OnPicked = append(options.OnPicked, co.OnPicked...)
+		}
+	}
 	if len(d.Filters) > 0 || len(options.Filters) > 0 {
 		newNodes := make([]Node, len(nodes))
 		for i, wc := range nodes {
@@ -45,6 +59,7 @@ func (d *Default) Select(ctx context.Context, opts ...SelectOption) (selected No
 	} else {
 		candidates = nodes
 	}
+	candidates = serving(candidates)
 
 	if len(candidates) == 0 {
 		return nil, nil, ErrNoAvailable
@@ -57,15 +72,46 @@ func (d *Default) Select(ctx context.Context, opts ...SelectOption) (selected No
 	if ok {
 		p.Node = wn.Raw()
 	}
+	for _, fn := range options.OnPicked {
+		fn(wn.Raw())
+	}
 	return wn.Raw(), done, nil
 }
 
+// serving excludes the draining nodes, unless all nodes are draining.
+func serving(nodes []WeightedNode) []WeightedNode {
+	var draining int
+	for _, n := range nodes {
+		if n.Metadata()[registry.StatusKey] == registry.StatusDraining {
+			draining++
+		}
+	}
+	if draining == 0 || draining == len(nodes) {
+		return nodes
+	}
+	newNodes := make([]WeightedNode, 0, len(nodes)-draining)
+	for _, n := range nodes {
+		if n.Metadata()[registry.StatusKey] != registry.StatusDraining {
+			newNodes = append(newNodes, n)
+		}
+	}
+	return newNodes
+}
+
 // Apply update nodes info.
 func (d *Default) Apply(nodes []Node) {
+	// the node builder and the balancer may keep the state of the nodes,
+	// they are notified to drop the state of the departed nodes.
+	if r, ok := d.NodeBuilder.(Rebalancer); ok {
+		r.Apply(nodes)
+	}
 	weightedNodes := make([]WeightedNode, 0, len(nodes))
 	for _, n := range nodes {
 		weightedNodes = append(weightedNodes, d.NodeBuilder.Build(n))
 	}
+	if r, ok := d.Balancer.(Rebalancer); ok {
+		r.Apply(nodes)
+	}
 	// TODO: Do not delete unchanged nodes
 	d.nodes.Store(weightedNodes)
 }
@@ -79,8 +125,12 @@ type DefaultBuilder struct {
 
 // Build create builder
 func (db *DefaultBuilder) Build() Selector {
+	nb := db.Node
+	if s, ok := nb.(ScopedNodeBuilder); ok {
+		nb = s.New()
+	}
 	return &Default{
-		NodeBuilder: db.Node,
+		NodeBuilder: nb,
 		Balancer:    db.Balancer.Build(),
 		Filters:     db.Filters,
 	}
@@ -0,0 +1,124 @@
+package filter
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/go-kratos/kratos/v2"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+const (
+	// ZoneKey is the default metadata key of the zone.
+	ZoneKey = "zone"
+	// RegionKey is the default metadata key of the region.
+	RegionKey = "region"
+)
+
+// LocalityOption is locality filter option.
+type LocalityOption func(*locality)
+
+// WithZone with the caller zone, which takes precedence over the app metadata.
+func WithZone(zone string) LocalityOption {
+	return func(l *locality) {
+		l.zone = zone
+	}
+}
+
+// WithRegion with the caller region, which takes precedence over the app metadata.
+func WithRegion(region string) LocalityOption {
+	return func(l *locality) {
+		l.region = region
+	}
+}
+
+// WithZoneKey with the metadata key of the zone.
+func WithZoneKey(key string) LocalityOption {
+	return func(l *locality) {
+		l.zoneKey = key
+	}
+}
+
+// WithRegionKey with the metadata key of the region.
+func WithRegionKey(key string) LocalityOption {
+	return func(l *locality) {
+		l.regionKey = key
+	}
+}
+
+// WithMinNodes with the number of nodes a locality needs to serve all of its traffic,
+// when it has fewer nodes, the missing percentage of the requests spill over
+// to the next locality.
+func WithMinNodes(n int) LocalityOption {
+	return func(l *locality) {
+		l.minNodes = n
+	}
+}
+
+type locality struct {
+	zone      string
+	region    string
+	zoneKey   string
+	regionKey string
+	minNodes  int
+}
+
+// Locality is locality aware filter, it prefers the nodes in the caller zone,
+// then the nodes in the caller region, and then all nodes.
+// The caller locality is read from the app metadata via kratos.FromContext,
+// unless it's set by the options.
+func Locality(opts ...LocalityOption) selector.Filter {
+	l := &locality{
+		zoneKey:   ZoneKey,
+		regionKey: RegionKey,
+		minNodes:  1,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	return func(ctx context.Context, nodes []selector.Node) []selector.Node {
+		zone, region := l.zone, l.region
+		if app, ok := kratos.FromContext(ctx); ok {
+			if md := app.Metadata(); md != nil {
+				if zone == "" {
+					zone = md[l.zoneKey]
+				}
+				if region == "" {
+					region = md[l.regionKey]
+				}
+			}
+		}
+		if zone == "" && region == "" {
+			return nodes
+		}
+		var zoneNodes, regionNodes []selector.Node
+		for _, n := range nodes {
+			md := n.Metadata()
+			if region != "" && md[l.regionKey] == region {
+				regionNodes = append(regionNodes, n)
+			}
+			if zone != "" && md[l.zoneKey] == zone && (region == "" || md[l.regionKey] == region) {
+				zoneNodes = append(zoneNodes, n)
+			}
+		}
+		if zone != "" && l.serve(len(zoneNodes)) {
+			return zoneNodes
+		}
+		if region != "" && l.serve(len(regionNodes)) {
+			return regionNodes
+		}
+		return nodes
+	}
+}
+
+// serve reports whether the request is served by a locality with n nodes.
+func (l *locality) serve(n int) bool {
+	if n == 0 {
+		return false
+	}
+	if n >= l.minNodes {
+		return true
+	}
+	// spill over the percentage of the missing capacity
+	return rand.Intn(l.minNodes) < n
+}
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+func newLocalityNodes(localities ...[2]string) []selector.Node {
+	nodes := make([]selector.Node, 0, len(localities))
+	for i, l := range localities {
+		addr := fmt.Sprintf("127.0.0.%d:9090", i+1)
+		nodes = append(nodes, selector.NewNode("http", addr, &registry.ServiceInstance{
+			ID:       addr,
+			Metadata: map[string]string{RegionKey: l[0], ZoneKey: l[1]},
+		}))
+	}
+	return nodes
+}
+
+func TestLocalityZone(t *testing.T) {
+	f := Locality(WithRegion("sh"), WithZone("sh-1"))
+	nodes := newLocalityNodes([2]string{"sh", "sh-1"}, [2]string{"sh", "sh-2"}, [2]string{"bj", "bj-1"})
+	nodes = f(context.Background(), nodes)
+	if len(nodes) != 1 || nodes[0].Address() != "127.0.0.1:9090" {
+		t.Errorf("expect %v, got %v", "127.0.0.1:9090", nodes)
+	}
+}
+
+func TestLocalityRegionFallback(t *testing.T) {
+	f := Locality(WithRegion("sh"), WithZone("sh-3"))
+	nodes := newLocalityNodes([2]string{"sh", "sh-1"}, [2]string{"sh", "sh-2"}, [2]string{"bj", "bj-1"})
+	nodes = f(context.Background(), nodes)
+	if len(nodes) != 2 {
+		t.Errorf("expect %v, got %v", 2, len(nodes))
+	}
+}
+
+func TestLocalityGlobalFallback(t *testing.T) {
+	f := Locality(WithRegion("gz"), WithZone("gz-1"))
+	nodes := newLocalityNodes([2]string{"sh", "sh-1"}, [2]string{"bj", "bj-1"})
+	nodes = f(context.Background(), nodes)
+	if len(nodes) != 2 {
+		t.Errorf("expect %v, got %v", 2, len(nodes))
+	}
+}
+
+func TestLocalitySpillover(t *testing.T) {
+	f := Locality(WithRegion("sh"), WithZone("sh-1"), WithMinNodes(4))
+	// one of four required nodes, about 75% of the requests spill over
+	nodes := newLocalityNodes([2]string{"sh", "sh-1"}, [2]string{"sh", "sh-2"}, [2]string{"sh", "sh-2"})
+	var local int
+	for i := 0; i < 1000; i++ {
+		if len(f(context.Background(), nodes)) == 1 {
+			local++
+		}
+	}
+	if local < 150 || local > 350 {
+		t.Errorf("expect about 250 local picks, got %d", local)
+	}
+}
+
+func TestLocalityAppMetadata(t *testing.T) {
+	f := Locality()
+	app := kratos.New(kratos.Metadata(map[string]string{RegionKey: "bj", ZoneKey: "bj-1"}))
+	nodes := newLocalityNodes([2]string{"sh", "sh-1"}, [2]string{"bj", "bj-1"})
+	nodes = f(kratos.NewContext(context.Background(), app), nodes)
+	if len(nodes) != 1 || nodes[0].Address() != "127.0.0.2:9090" {
+		t.Errorf("expect %v, got %v", "127.0.0.2:9090", nodes)
+	}
+	// without locality, all nodes are kept
+	nodes = newLocalityNodes([2]string{"sh", "sh-1"}, [2]string{"bj", "bj-1"})
+	if len(f(context.Background(), nodes)) != 2 {
+		t.Errorf("expect %v, got %v", 2, len(nodes))
+	}
+}
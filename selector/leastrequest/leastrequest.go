@@ -0,0 +1,237 @@
+package leastrequest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+const (
+	// Name is least request balancer name
+	Name = "leastrequest"
+
+	defaultWeight = 100
+)
+
+var (
+	_ selector.Balancer          = &Balancer{}
+	_ selector.WeightedNode      = &Node{}
+	_ selector.ScopedNodeBuilder = &NodeBuilder{}
+)
+
+// WithFilter with select filters
+func WithFilter(filters ...selector.Filter) Option {
+	return func(o *options) {
+		o.filters = filters
+	}
+}
+
+// WithChoices with the number of nodes sampled on each pick,
+// all nodes are compared if it's not greater than zero.
+func WithChoices(n int) Option {
+	return func(o *options) {
+		o.choices = n
+	}
+}
+
+// Option is least request builder option.
+type Option func(o *options)
+
+// options is least request builder options
+type options struct {
+	filters []selector.Filter
+	choices int
+}
+
+// New creates a least request selector.
+func New(opts ...Option) selector.Selector {
+	return NewBuilder(opts...).Build()
+}
+
+// Balancer is a least request balancer, it picks the node with
+// the fewest in-flight requests relative to its weight.
+type Balancer struct {
+	choices int
+
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// Pick is pick a weighted node.
+func (b *Balancer) Pick(_ context.Context, nodes []selector.WeightedNode) (selector.WeightedNode, selector.DoneFunc, error) {
+	if len(nodes) == 0 {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	var (
+		selected selector.WeightedNode
+		load     float64
+	)
+	b.mu.Lock()
+	if b.choices > 0 && b.choices < len(nodes) {
+		// power of n choices: sample n distinct nodes
+		for _, i := range sample(b.r, len(nodes), b.choices) {
+			if l := loadOf(nodes[i]); selected == nil || l < load {
+				selected, load = nodes[i], l
+			}
+		}
+	} else {
+		// start from a random offset so ties are broken randomly
+		offset := b.r.Intn(len(nodes))
+		for i := range nodes {
+			n := nodes[(offset+i)%len(nodes)]
+			if l := loadOf(n); selected == nil || l < load {
+				selected, load = n, l
+			}
+		}
+	}
+	b.mu.Unlock()
+	d := selected.Pick()
+	return selected, d, nil
+}
+
+// sample returns k distinct indices in [0, n) by Floyd's algorithm,
+// which takes O(k) instead of O(n) for the permutation.
+func sample(r *rand.Rand, n, k int) []int {
+	picked := make([]int, 0, k)
+	for j := n - k; j < n; j++ {
+		i := r.Intn(j + 1)
+		for _, p := range picked {
+			if p == i {
+				i = j
+				break
+			}
+		}
+		picked = append(picked, i)
+	}
+	return picked
+}
+
+func loadOf(n selector.WeightedNode) float64 {
+	inflight := inflightOf(n)
+	w := n.Weight()
+	if w <= 0 {
+		w = defaultWeight
+	}
+	return float64(inflight+1) / w
+}
+
+// inflightOf returns the in-flight requests of the node,
+// which may be wrapped by other nodes, e.g. the slow start node.
+func inflightOf(n selector.WeightedNode) int64 {
+	for {
+		switch v := n.(type) {
+		case *Node:
+			return v.Inflight()
+		case interface{ Unwrap() selector.WeightedNode }:
+			n = v.Unwrap()
+		default:
+			return 0
+		}
+	}
+}
+
+// Node is a node which counts its in-flight requests.
+type Node struct {
+	selector.Node
+
+	inflight *int64
+	lastPick int64
+}
+
+// Pick the node, the in-flight count is decreased when the request is done.
+func (n *Node) Pick() selector.DoneFunc {
+	atomic.StoreInt64(&n.lastPick, time.Now().UnixNano())
+	atomic.AddInt64(n.inflight, 1)
+	var once int32
+	return func(ctx context.Context, di selector.DoneInfo) {
+		if atomic.CompareAndSwapInt32(&once, 0, 1) {
+			atomic.AddInt64(n.inflight, -1)
+		}
+	}
+}
+
+// Inflight returns the number of in-flight requests.
+func (n *Node) Inflight() int64 {
+	return atomic.LoadInt64(n.inflight)
+}
+
+// Weight is node effective weight
+func (n *Node) Weight() float64 {
+	if n.InitialWeight() != nil {
+		return float64(*n.InitialWeight())
+	}
+	return defaultWeight
+}
+
+// PickElapsed is time elapsed since the latest pick
+func (n *Node) PickElapsed() time.Duration {
+	return time.Duration(time.Now().UnixNano() - atomic.LoadInt64(&n.lastPick))
+}
+
+// Raw returns the original node
+func (n *Node) Raw() selector.Node {
+	return n.Node
+}
+
+// NodeBuilder is least request node builder, the in-flight counts are
+// shared by the nodes with the same address, so they survive rebuilding
+// the nodes when the membership changes. The counts are kept per selector,
+// as a new builder is created for each selector by New.
+type NodeBuilder struct {
+	counters sync.Map
+}
+
+// New creates a node builder of a selector.
+func (b *NodeBuilder) New() selector.WeightedNodeBuilder {
+	return &NodeBuilder{}
+}
+
+// Apply drops the in-flight counts of the departed nodes.
+func (b *NodeBuilder) Apply(nodes []selector.Node) {
+	addrs := make(map[string]struct{}, len(nodes))
+	for _, n := range nodes {
+		addrs[n.Address()] = struct{}{}
+	}
+	b.counters.Range(func(key, _ interface{}) bool {
+		if _, ok := addrs[key.(string)]; !ok {
+			b.counters.Delete(key)
+		}
+		return true
+	})
+}
+
+// Build create a weighted node.
+func (b *NodeBuilder) Build(n selector.Node) selector.WeightedNode {
+	c, _ := b.counters.LoadOrStore(n.Address(), new(int64))
+	return &Node{Node: n, inflight: c.(*int64)}
+}
+
+// NewBuilder returns a selector builder with least request balancer
+func NewBuilder(opts ...Option) selector.Builder {
+	var option options
+	for _, opt := range opts {
+		opt(&option)
+	}
+	return &selector.DefaultBuilder{
+		Filters:  option.filters,
+		Balancer: &Builder{Choices: option.choices},
+		Node:     &NodeBuilder{},
+	}
+}
+
+// Builder is least request builder
+type Builder struct {
+	Choices int
+}
+
+// Build creates Balancer
+func (b *Builder) Build() selector.Balancer {
+	return &Balancer{
+		choices: b.Choices,
+		r:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
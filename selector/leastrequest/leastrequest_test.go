@@ -0,0 +1,183 @@
+package leastrequest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+func newNodes(n int) []selector.Node {
+	nodes := make([]selector.Node, 0, n)
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.%d:8080", i+1)
+		nodes = append(nodes, selector.NewNode("http", addr, &registry.ServiceInstance{ID: addr}))
+	}
+	return nodes
+}
+
+func TestLeastRequest(t *testing.T) {
+	s := New()
+	s.Apply(newNodes(3))
+	dones := make(map[string][]selector.DoneFunc)
+	// without any done, the picks are spread evenly
+	for i := 0; i < 30; i++ {
+		n, done, err := s.Select(context.Background())
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		dones[n.Address()] = append(dones[n.Address()], done)
+	}
+	for addr, ds := range dones {
+		if len(ds) != 10 {
+			t.Errorf("expect 10 picks on %s, got %d", addr, len(ds))
+		}
+	}
+	// finish the requests of one node, it has the fewest in-flight requests now
+	addr := "127.0.0.2:8080"
+	for _, done := range dones[addr] {
+		done(context.Background(), selector.DoneInfo{})
+	}
+	n, _, err := s.Select(context.Background())
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if n.Address() != addr {
+		t.Errorf("expect %s, got %s", addr, n.Address())
+	}
+}
+
+func TestInflightSurvivesApply(t *testing.T) {
+	s := New()
+	nodes := newNodes(2)
+	s.Apply(nodes)
+	n, done, err := s.Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rebuild the nodes, the in-flight request is still counted
+	s.Apply(nodes)
+	for i := 0; i < 5; i++ {
+		m, d, err := s.Select(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && m.Address() == n.Address() {
+			t.Errorf("expect a node other than %s", n.Address())
+		}
+		d(context.Background(), selector.DoneInfo{})
+	}
+	done(context.Background(), selector.DoneInfo{})
+}
+
+func TestChoices(t *testing.T) {
+	s := New(WithChoices(2))
+	s.Apply(newNodes(10))
+	for i := 0; i < 100; i++ {
+		n, done, err := s.Select(context.Background())
+		if err != nil || n == nil {
+			t.Fatalf("expect node, got %v %v", n, err)
+		}
+		done(context.Background(), selector.DoneInfo{})
+	}
+}
+
+func TestSample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		picked := sample(r, 5, 3)
+		seen := make(map[int]bool)
+		for _, p := range picked {
+			if p < 0 || p >= 5 || seen[p] {
+				t.Fatalf("expect 3 distinct indices in [0, 5), got %v", picked)
+			}
+			seen[p] = true
+		}
+	}
+}
+
+func TestPruneCounters(t *testing.T) {
+	b := &NodeBuilder{}
+	nodes := newNodes(3)
+	for _, n := range nodes {
+		b.Build(n)
+	}
+	b.Apply(nodes[:1])
+	var count int
+	b.counters.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("expect 1 counter, got %d", count)
+	}
+}
+
+type wrappedNode struct {
+	selector.WeightedNode
+}
+
+func (n *wrappedNode) Unwrap() selector.WeightedNode { return n.WeightedNode }
+
+func TestInflightOfWrapped(t *testing.T) {
+	n := (&NodeBuilder{}).Build(selector.NewNode("http", "127.0.0.1:8080", nil))
+	done := n.Pick()
+	defer done(context.Background(), selector.DoneInfo{})
+	if v := inflightOf(&wrappedNode{n}); v != 1 {
+		t.Errorf("expect %v, got %v", 1, v)
+	}
+}
+
+func TestNode(t *testing.T) {
+	b := &NodeBuilder{}
+	n := b.Build(selector.NewNode("http", "127.0.0.1:8080", nil)).(*Node)
+	done := n.Pick()
+	if n.Inflight() != 1 {
+		t.Errorf("expect 1 in-flight request, got %d", n.Inflight())
+	}
+	done(context.Background(), selector.DoneInfo{})
+	done(context.Background(), selector.DoneInfo{})
+	if n.Inflight() != 0 {
+		t.Errorf("expect 0 in-flight request, got %d", n.Inflight())
+	}
+	if n.Weight() != defaultWeight {
+		t.Errorf("expect weight %d, got %f", defaultWeight, n.Weight())
+	}
+	if n.Raw() == nil || n.PickElapsed() < 0 {
+		t.Errorf("unexpected node %v", n)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	b := &Builder{}
+	_, _, err := b.Build().Pick(context.Background(), []selector.WeightedNode{})
+	if err == nil {
+		t.Errorf("expect no available node error")
+	}
+}
+
+func TestBuilderPerSelector(t *testing.T) {
+	b := NewBuilder()
+	s1, s2 := b.Build(), b.Build()
+	nodes := newNodes(2)
+	s1.Apply(nodes)
+	_, done, err := s1.Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the nodes of another service don't prune the counts of the selector.
+	s2.Apply(newNodes(1)[:0])
+	nb := s1.(*selector.Default).NodeBuilder.(*NodeBuilder)
+	var inflight int64
+	nb.counters.Range(func(_, v interface{}) bool {
+		inflight += *v.(*int64)
+		return true
+	})
+	if inflight != 1 {
+		t.Errorf("expect %v, got %v", 1, inflight)
+	}
+	done(context.Background(), selector.DoneInfo{})
+}
@@ -0,0 +1,167 @@
+package slowstart
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+var (
+	_ selector.WeightedNode      = &Node{}
+	_ selector.ScopedNodeBuilder = &Builder{}
+)
+
+// Curve maps the warmup progress in [0, 1] to the weight factor in [0, 1].
+type Curve func(progress float64) float64
+
+// Linear ramps the weight linearly.
+func Linear(progress float64) float64 {
+	return progress
+}
+
+// Aggressive ramps the weight faster at the beginning as the aggression grows,
+// it's linear if the aggression is 1.
+func Aggressive(aggression float64) Curve {
+	if aggression <= 0 {
+		aggression = 1
+	}
+	return func(progress float64) float64 {
+		return math.Pow(progress, 1/aggression)
+	}
+}
+
+// Option is slow start option.
+type Option func(*options)
+
+// WithWindow with the duration of the warmup.
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// WithMinWeight with the min fraction of the weight during the warmup.
+func WithMinWeight(f float64) Option {
+	return func(o *options) {
+		o.minWeight = f
+	}
+}
+
+// WithCurve with the curve of the warmup.
+func WithCurve(c Curve) Option {
+	return func(o *options) {
+		o.curve = c
+	}
+}
+
+type options struct {
+	window    time.Duration
+	minWeight float64
+	curve     Curve
+}
+
+// Builder wraps a weighted node builder, the effective weight of a newly discovered node
+// ramps up from the min weight to the full weight over the window.
+// The discovery time is shared by the nodes of the same instance ID and address,
+// so it survives rebuilding the nodes when the membership changes,
+// and a restarted instance on the same address warms up again.
+// It's kept per selector, as a new builder is created for each selector by New.
+//
+//	&selector.DefaultBuilder{
+//		Balancer: &wrr.Builder{},
+//		Node:     slowstart.NewBuilder(&direct.Builder{}),
+//	}
+type Builder struct {
+	builder selector.WeightedNodeBuilder
+	opts    options
+	now     func() time.Time
+
+	starts sync.Map
+}
+
+// NewBuilder creates a slow start node builder.
+func NewBuilder(b selector.WeightedNodeBuilder, opts ...Option) *Builder {
+	o := options{
+		window:    30 * time.Second,
+		minWeight: 0.1,
+		curve:     Linear,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Builder{builder: b, opts: o, now: time.Now}
+}
+
+// New creates a node builder of a selector, so that the discovery time is kept per selector.
+func (b *Builder) New() selector.WeightedNodeBuilder {
+	builder := b.builder
+	if s, ok := builder.(selector.ScopedNodeBuilder); ok {
+		builder = s.New()
+	}
+	return &Builder{builder: builder, opts: b.opts, now: b.now}
+}
+
+// Apply drops the discovery time of the departed nodes,
+// and passes the nodes to the wrapped builder if it's a rebalancer.
+func (b *Builder) Apply(nodes []selector.Node) {
+	keys := make(map[string]struct{}, len(nodes))
+	for _, n := range nodes {
+		keys[key(n)] = struct{}{}
+	}
+	b.starts.Range(func(k, _ interface{}) bool {
+		if _, ok := keys[k.(string)]; !ok {
+			b.starts.Delete(k)
+		}
+		return true
+	})
+	if r, ok := b.builder.(selector.Rebalancer); ok {
+		r.Apply(nodes)
+	}
+}
+
+// key returns the instance ID and the address of the node.
+func key(n selector.Node) string {
+	if i, ok := n.(interface{ ID() string }); ok && i.ID() != "" {
+		return i.ID() + "@" + n.Address()
+	}
+	return n.Address()
+}
+
+// Build create a weighted node.
+func (b *Builder) Build(n selector.Node) selector.WeightedNode {
+	start, _ := b.starts.LoadOrStore(key(n), b.now())
+	return &Node{
+		WeightedNode: b.builder.Build(n),
+		b:            b,
+		start:        start.(time.Time),
+	}
+}
+
+// Node is a slow start node.
+type Node struct {
+	selector.WeightedNode
+
+	b     *Builder
+	start time.Time
+}
+
+// Unwrap returns the wrapped node.
+func (n *Node) Unwrap() selector.WeightedNode {
+	return n.WeightedNode
+}
+
+// Weight is the weight of the wrapped node scaled by the warmup progress.
+func (n *Node) Weight() float64 {
+	weight := n.WeightedNode.Weight()
+	elapsed := n.b.now().Sub(n.start)
+	if n.b.opts.window <= 0 || elapsed >= n.b.opts.window {
+		return weight
+	}
+	f := n.b.opts.curve(float64(elapsed) / float64(n.b.opts.window))
+	if f < n.b.opts.minWeight {
+		f = n.b.opts.minWeight
+	}
+	return weight * f
+}
@@ -0,0 +1,117 @@
+package slowstart
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/node/direct"
+)
+
+func TestSlowStart(t *testing.T) {
+	now := time.Now()
+	b := NewBuilder(&direct.Builder{}, WithWindow(10*time.Second), WithMinWeight(0.1))
+	b.now = func() time.Time { return now }
+	n := b.Build(selector.NewNode("http", "127.0.0.1:8080", &registry.ServiceInstance{
+		Metadata: map[string]string{"weight": "100"},
+	}))
+	if w := n.Weight(); w != 10 {
+		t.Errorf("expect %v, got %v", 10, w)
+	}
+	now = now.Add(5 * time.Second)
+	if w := n.Weight(); w != 50 {
+		t.Errorf("expect %v, got %v", 50, w)
+	}
+	// rebuilt node keeps the discovery time
+	n = b.Build(selector.NewNode("http", "127.0.0.1:8080", &registry.ServiceInstance{
+		Metadata: map[string]string{"weight": "100"},
+	}))
+	if w := n.Weight(); w != 50 {
+		t.Errorf("expect %v, got %v", 50, w)
+	}
+	now = now.Add(5 * time.Second)
+	if w := n.Weight(); w != 100 {
+		t.Errorf("expect %v, got %v", 100, w)
+	}
+	if n.Raw().Address() != "127.0.0.1:8080" {
+		t.Errorf("expect %v, got %v", "127.0.0.1:8080", n.Raw().Address())
+	}
+}
+
+func TestAggressive(t *testing.T) {
+	if v := Aggressive(1)(0.25); v != 0.25 {
+		t.Errorf("expect %v, got %v", 0.25, v)
+	}
+	if v := Aggressive(2)(0.25); math.Abs(v-0.5) > 1e-9 {
+		t.Errorf("expect %v, got %v", 0.5, v)
+	}
+}
+
+func TestSlowStart_Apply(t *testing.T) {
+	now := time.Now()
+	b := NewBuilder(&direct.Builder{}, WithWindow(10*time.Second), WithMinWeight(0.1))
+	b.now = func() time.Time { return now }
+	node := func(id string) selector.Node {
+		return selector.NewNode("http", "127.0.0.1:8080", &registry.ServiceInstance{
+			ID:       id,
+			Metadata: map[string]string{"weight": "100"},
+		})
+	}
+	b.Apply([]selector.Node{node("1")})
+	b.Build(node("1"))
+	now = now.Add(5 * time.Second)
+	// the instance restarted on the same address warms up again
+	b.Apply([]selector.Node{node("2")})
+	if w := b.Build(node("2")).Weight(); w != 10 {
+		t.Errorf("expect %v, got %v", 10, w)
+	}
+	var count int
+	b.starts.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("expect %v, got %v", 1, count)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	inner := (&direct.Builder{}).Build(selector.NewNode("http", "127.0.0.1:8080", nil))
+	n := NewBuilder(&direct.Builder{}).Build(selector.NewNode("http", "127.0.0.1:8080", nil)).(*Node)
+	if n.Unwrap() == nil || n.Unwrap().Address() != inner.Address() {
+		t.Errorf("expect %v, got %v", inner.Address(), n.Unwrap())
+	}
+}
+
+func TestSlowStart_PerSelector(t *testing.T) {
+	now := time.Now()
+	b := NewBuilder(&direct.Builder{}, WithWindow(10*time.Second), WithMinWeight(0.1))
+	b.now = func() time.Time { return now }
+	db := &selector.DefaultBuilder{Balancer: &mockBalancerBuilder{}, Node: b}
+	s1, s2 := db.Build().(*selector.Default), db.Build().(*selector.Default)
+	n := selector.NewNode("http", "127.0.0.1:8080", &registry.ServiceInstance{
+		ID:       "1",
+		Metadata: map[string]string{"weight": "100"},
+	})
+	s1.Apply([]selector.Node{n})
+	now = now.Add(5 * time.Second)
+	// the nodes of another selector don't restart the warmup.
+	s2.Apply(nil)
+	s1.Apply([]selector.Node{n})
+	if w := s1.NodeBuilder.Build(n).Weight(); w != 50 {
+		t.Errorf("expect %v, got %v", 50, w)
+	}
+}
+
+type mockBalancerBuilder struct{}
+
+func (*mockBalancerBuilder) Build() selector.Balancer { return &mockBalancer{} }
+
+type mockBalancer struct{}
+
+func (*mockBalancer) Pick(_ context.Context, nodes []selector.WeightedNode) (selector.WeightedNode, selector.DoneFunc, error) {
+	return nodes[0], func(context.Context, selector.DoneInfo) {}, nil
+}
@@ -1,8 +1,12 @@
 package selector
 
+import "context"
+
 // SelectOptions is Select Options.
 type SelectOptions struct {
 	Filters []Filter
+	// OnPicked is called with the selected node.
+	OnPicked []func(Node)
 }
 
 // SelectOption is Selector option.
@@ -14,3 +18,28 @@ func WithFilter(fn ...Filter) SelectOption {
 		opts.Filters = fn
 	}
 }
+
+// WithOnPicked with a callback which is called with the selected node.
+func WithOnPicked(fn func(Node)) SelectOption {
+	return func(opts *SelectOptions) {
+		opts.OnPicked = append(opts.OnPicked, fn)
+	}
+}
+
+type selectOptionsKey struct{}
+
+// NewSelectOptionsContext returns a new Context that carries select options,
+// the Default selector applies them in addition to the options passed to Select.
+func NewSelectOptionsContext(ctx context.Context, opts ...SelectOption) context.Context {
+	prev, _ := ctx.Value(selectOptionsKey{}).([]SelectOption)
+	all := make([]SelectOption, 0, len(prev)+len(opts))
+	all = append(all, prev...)
+	all = append(all, opts...)
+	return context.WithValue(ctx, selectOptionsKey{}, all)
+}
+
+// FromSelectOptionsContext returns the select options stored in ctx, if any.
+func FromSelectOptionsContext(ctx context.Context) (opts []SelectOption, ok bool) {
+	opts, ok = ctx.Value(selectOptionsKey{}).([]SelectOption)
+	return
+}
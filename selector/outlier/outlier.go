@@ -0,0 +1,325 @@
+package outlier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/aegis/pkg/window"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+const (
+	// the number of buckets of the failure rate window
+	buckets = 10
+
+	defaultWindow = 10 * time.Second
+
+	eventEject  = "eject"
+	eventReturn = "return"
+)
+
+var (
+	_ selector.Balancer        = &Balancer{}
+	_ selector.BalancerBuilder = &Builder{}
+)
+
+// Option is outlier detection option.
+type Option func(*options)
+
+// WithConsecutiveErrors with the number of consecutive failures to eject a node,
+// it's disabled if n is not greater than zero.
+func WithConsecutiveErrors(n int64) Option {
+	return func(o *options) {
+		o.consecutive = n
+	}
+}
+
+// WithFailureRate with the failure rate to eject a node within the window,
+// it's checked only when the node has at least minRequests requests.
+func WithFailureRate(rate float64, minRequests int64) Option {
+	return func(o *options) {
+		o.failureRate = rate
+		o.minRequests = minRequests
+	}
+}
+
+// WithWindow with the window of the failure rate, the default is used if it's too short.
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// WithBaseEjectionTime with the base ejection time,
+// it's doubled every time the node is ejected again.
+func WithBaseEjectionTime(d time.Duration) Option {
+	return func(o *options) {
+		o.baseEjection = d
+	}
+}
+
+// WithMaxEjectionTime with the max ejection time.
+func WithMaxEjectionTime(d time.Duration) Option {
+	return func(o *options) {
+		o.maxEjection = d
+	}
+}
+
+// WithMaxEjectionPercent with the max percent of the candidate nodes which can be ejected,
+// at least one node can be ejected, and the pool is never emptied.
+func WithMaxEjectionPercent(p int) Option {
+	return func(o *options) {
+		o.maxPercent = p
+	}
+}
+
+// WithErrorHandler with the func which reports whether the error is a failure.
+func WithErrorHandler(fn func(err error) bool) Option {
+	return func(o *options) {
+		o.isFailure = fn
+	}
+}
+
+// WithEvents with the counter of ejection and return events,
+// which is labeled by the event and the node address.
+func WithEvents(c metrics.Counter) Option {
+	return func(o *options) {
+		o.events = c
+	}
+}
+
+type options struct {
+	consecutive  int64
+	failureRate  float64
+	minRequests  int64
+	window       time.Duration
+	baseEjection time.Duration
+	maxEjection  time.Duration
+	maxPercent   int
+	isFailure    func(err error) bool
+	events       metrics.Counter
+}
+
+// Builder is outlier detection balancer builder.
+type Builder struct {
+	builder selector.BalancerBuilder
+	d       *detector
+}
+
+// NewBuilder returns a balancer builder which passively ejects the nodes
+// which keep failing from the nodes picked by the wrapped balancer.
+// The state is shared by all the balancers it builds.
+func NewBuilder(b selector.BalancerBuilder, opts ...Option) *Builder {
+	o := options{
+		consecutive:  5,
+		failureRate:  0.5,
+		minRequests:  20,
+		window:       defaultWindow,
+		baseEjection: 30 * time.Second,
+		maxEjection:  5 * time.Minute,
+		maxPercent:   10,
+		isFailure:    isFailure,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.window/buckets <= 0 {
+		o.window = defaultWindow
+	}
+	return &Builder{
+		builder: b,
+		d:       &detector{opts: o, stats: make(map[string]*stat)},
+	}
+}
+
+// Build creates Balancer
+func (b *Builder) Build() selector.Balancer {
+	return &Balancer{balancer: b.builder.Build(), d: b.d}
+}
+
+// Balancer is outlier detection balancer.
+type Balancer struct {
+	balancer selector.Balancer
+	d        *detector
+}
+
+// Pick is pick a weighted node from the nodes which are not ejected.
+func (b *Balancer) Pick(ctx context.Context, nodes []selector.WeightedNode) (selector.WeightedNode, selector.DoneFunc, error) {
+	selected, done, err := b.balancer.Pick(ctx, b.d.filter(nodes))
+	if err != nil {
+		return nil, nil, err
+	}
+	addr := selected.Address()
+	return selected, func(ctx context.Context, di selector.DoneInfo) {
+		b.d.report(addr, di)
+		done(ctx, di)
+	}, nil
+}
+
+// Apply passes the nodes to the wrapped balancer if it's a rebalancer.
+func (b *Balancer) Apply(nodes []selector.Node) {
+	if r, ok := b.balancer.(selector.Rebalancer); ok {
+		r.Apply(nodes)
+	}
+}
+
+// isFailure is the default error handler, which treats 5xx errors as failures.
+func isFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return errors.FromError(err).Code >= 500
+}
+
+type stat struct {
+	consecutive  int64
+	requests     window.RollingCounter
+	failures     window.RollingCounter
+	ejections    int
+	ejectedUntil time.Time
+	lastEjection time.Time
+	lastSeen     time.Time
+}
+
+type detector struct {
+	opts options
+
+	mu        sync.Mutex
+	stats     map[string]*stat
+	lastSweep time.Time
+}
+
+func (d *detector) stat(addr string, now time.Time) *stat {
+	s, ok := d.stats[addr]
+	if !ok {
+		s = &stat{lastSeen: now}
+		d.reset(s)
+		d.stats[addr] = s
+	}
+	return s
+}
+
+// sweep evicts the stats of the nodes which have not been seen for longer
+// than the max ejection time, e.g. the nodes which left the service.
+func (d *detector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.opts.window {
+		return
+	}
+	d.lastSweep = now
+	for addr, s := range d.stats {
+		if now.Sub(s.lastSeen) > d.opts.maxEjection+d.opts.window {
+			delete(d.stats, addr)
+		}
+	}
+}
+
+func (d *detector) reset(s *stat) {
+	opts := window.RollingCounterOpts{Size: buckets, BucketDuration: d.opts.window / buckets}
+	s.consecutive = 0
+	s.requests = window.NewRollingCounter(opts)
+	s.failures = window.NewRollingCounter(opts)
+}
+
+// filter removes the ejected nodes, and returns the nodes whose ejection expired.
+func (d *detector) filter(nodes []selector.WeightedNode) []selector.WeightedNode {
+	now := time.Now()
+	max := len(nodes) * d.opts.maxPercent / 100
+	if max < 1 {
+		max = 1
+	}
+	if max > len(nodes)-1 {
+		max = len(nodes) - 1
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var (
+		ejected   int
+		available = make([]selector.WeightedNode, 0, len(nodes))
+	)
+	d.sweep(now)
+	for _, n := range nodes {
+		s, ok := d.stats[n.Address()]
+		if ok {
+			s.lastSeen = now
+		}
+		if !ok || s.ejectedUntil.IsZero() {
+			available = append(available, n)
+			continue
+		}
+		if now.After(s.ejectedUntil) {
+			s.ejectedUntil = time.Time{}
+			d.reset(s)
+			d.event(eventReturn, n.Address())
+			available = append(available, n)
+			continue
+		}
+		if ejected >= max {
+			// over the cap, the node is kept in the pool
+			available = append(available, n)
+			continue
+		}
+		ejected++
+	}
+	return available
+}
+
+func (d *detector) report(addr string, di selector.DoneInfo) {
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.stat(addr, now)
+	if !s.ejectedUntil.IsZero() {
+		return
+	}
+	s.requests.Add(1)
+	if !d.opts.isFailure(di.Err) {
+		s.consecutive = 0
+		return
+	}
+	s.consecutive++
+	s.failures.Add(1)
+	eject := d.opts.consecutive > 0 && s.consecutive >= d.opts.consecutive
+	if !eject && d.opts.failureRate > 0 {
+		if requests := s.requests.Value(); requests >= d.opts.minRequests && requests > 0 {
+			eject = float64(s.failures.Value())/float64(requests) >= d.opts.failureRate
+		}
+	}
+	if !eject {
+		return
+	}
+	if !s.lastEjection.IsZero() && now.Sub(s.lastEjection) > d.opts.maxEjection+d.ejection(s.ejections) {
+		// the node has been healthy long enough since its last ejection
+		s.ejections = 0
+	}
+	s.ejections++
+	s.lastEjection = now
+	s.ejectedUntil = now.Add(d.ejection(s.ejections))
+	d.event(eventEject, addr)
+}
+
+// ejection returns the ejection time, which grows exponentially with the ejections.
+func (d *detector) ejection(ejections int) time.Duration {
+	t := d.opts.baseEjection
+	for i := 1; i < ejections && t < d.opts.maxEjection; i++ {
+		t *= 2
+	}
+	if t > d.opts.maxEjection {
+		t = d.opts.maxEjection
+	}
+	return t
+}
+
+func (d *detector) event(event, addr string) {
+	if event == eventEject {
+		log.Warnf("[selector] outlier node ejected: %s", addr)
+	} else {
+		log.Infof("[selector] outlier node returned: %s", addr)
+	}
+	if d.opts.events != nil {
+		d.opts.events.With(event, addr).Inc()
+	}
+}
@@ -0,0 +1,158 @@
+package outlier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/node/direct"
+	"github.com/go-kratos/kratos/v2/selector/wrr"
+)
+
+type mockCounter struct {
+	lvs    []string
+	counts map[string]int
+}
+
+func (c *mockCounter) With(lvs ...string) metrics.Counter {
+	return &mockCounter{lvs: lvs, counts: c.counts}
+}
+
+func (c *mockCounter) Inc() {
+	c.counts[c.lvs[0]]++
+}
+
+func (c *mockCounter) Add(delta float64) {}
+
+func newSelector(n int, opts ...Option) selector.Selector {
+	s := (&selector.DefaultBuilder{
+		Node:     &direct.Builder{},
+		Balancer: NewBuilder(&wrr.Builder{}, opts...),
+	}).Build()
+	nodes := make([]selector.Node, 0, n)
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.%d:8080", i+1)
+		nodes = append(nodes, selector.NewNode("http", addr, &registry.ServiceInstance{ID: addr}))
+	}
+	s.Apply(nodes)
+	return s
+}
+
+func call(t *testing.T, s selector.Selector, bad string) string {
+	n, done, err := s.Select(context.Background())
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	var di selector.DoneInfo
+	if n.Address() == bad {
+		di.Err = errors.ServiceUnavailable("UNAVAILABLE", "")
+	}
+	done(context.Background(), di)
+	return n.Address()
+}
+
+func TestConsecutiveErrors(t *testing.T) {
+	events := &mockCounter{counts: make(map[string]int)}
+	bad := "127.0.0.1:8080"
+	s := newSelector(2,
+		WithConsecutiveErrors(3),
+		WithBaseEjectionTime(50*time.Millisecond),
+		WithMaxEjectionPercent(50),
+		WithEvents(events),
+	)
+	for i := 0; i < 6; i++ {
+		call(t, s, bad)
+	}
+	if events.counts[eventEject] != 1 {
+		t.Fatalf("expect the node to be ejected, got %v", events.counts)
+	}
+	for i := 0; i < 10; i++ {
+		if addr := call(t, s, ""); addr == bad {
+			t.Fatalf("expect the ejected node not to be picked")
+		}
+	}
+	time.Sleep(60 * time.Millisecond)
+	picked := false
+	for i := 0; i < 4; i++ {
+		if call(t, s, "") == bad {
+			picked = true
+		}
+	}
+	if !picked || events.counts[eventReturn] != 1 {
+		t.Errorf("expect the node to return, got %v", events.counts)
+	}
+}
+
+func TestFailureRate(t *testing.T) {
+	events := &mockCounter{counts: make(map[string]int)}
+	bad := "127.0.0.1:8080"
+	s := newSelector(2,
+		WithConsecutiveErrors(0),
+		WithFailureRate(0.5, 4),
+		WithEvents(events),
+	)
+	for i := 0; i < 8; i++ {
+		call(t, s, bad)
+	}
+	if events.counts[eventEject] != 1 {
+		t.Errorf("expect the node to be ejected, got %v", events.counts)
+	}
+}
+
+func TestMaxEjectionPercent(t *testing.T) {
+	s := newSelector(2, WithConsecutiveErrors(1), WithMaxEjectionPercent(100))
+	// every node fails, but the pool is never emptied
+	for i := 0; i < 10; i++ {
+		n, done, err := s.Select(context.Background())
+		if err != nil || n == nil {
+			t.Fatalf("expect node, got %v %v", n, err)
+		}
+		done(context.Background(), selector.DoneInfo{Err: errors.InternalServer("INTERNAL", "")})
+	}
+}
+
+func TestEjectionTime(t *testing.T) {
+	d := &detector{opts: options{baseEjection: time.Second, maxEjection: 5 * time.Second}}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := d.ejection(i + 1); got != want {
+			t.Errorf("expect %s, got %s", want, got)
+		}
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	if isFailure(nil) || isFailure(context.Canceled) || isFailure(errors.BadRequest("BAD", "")) {
+		t.Errorf("expect not failure")
+	}
+	if !isFailure(errors.ServiceUnavailable("UNAVAILABLE", "")) {
+		t.Errorf("expect failure")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	b := NewBuilder(&wrr.Builder{}, WithWindow(0))
+	if b.d.opts.window != defaultWindow {
+		t.Errorf("expect window %s, got %s", defaultWindow, b.d.opts.window)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	b := NewBuilder(&wrr.Builder{}, WithWindow(time.Second), WithMaxEjectionTime(time.Second))
+	d := b.d
+	now := time.Now()
+	d.report("127.0.0.1:8080", selector.DoneInfo{})
+	d.report("127.0.0.2:8080", selector.DoneInfo{})
+	d.stats["127.0.0.2:8080"].lastSeen = now.Add(-time.Minute)
+	d.sweep(now)
+	if _, ok := d.stats["127.0.0.2:8080"]; ok {
+		t.Error("expect the stat of the departed node to be evicted")
+	}
+	if _, ok := d.stats["127.0.0.1:8080"]; !ok {
+		t.Error("expect the stat of the node to be kept")
+	}
+}
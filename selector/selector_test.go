@@ -174,3 +174,64 @@ func TestDefault(t *testing.T) {
 		t.Errorf("expect %v, got %v", nil, n)
 	}
 }
+
+func TestSelectOptionsContext(t *testing.T) {
+	builder := DefaultBuilder{
+		Node:     &mockWeightedNodeBuilder{},
+		Balancer: &mockBalancerBuilder{},
+	}
+	selector := builder.Build()
+	selector.Apply([]Node{
+		NewNode("http", "127.0.0.1:8080", &registry.ServiceInstance{Version: "v1.0.0"}),
+		NewNode("http", "127.0.0.1:9090", &registry.ServiceInstance{Version: "v2.0.0"}),
+	})
+	var picked Node
+	ctx := NewSelectOptionsContext(context.Background(), WithFilter(mockFilter("v2.0.0")))
+	ctx = NewSelectOptionsContext(ctx, WithOnPicked(func(n Node) { picked = n }))
+	if opts, ok := FromSelectOptionsContext(ctx); !ok || len(opts) != 2 {
+		t.Fatalf("expect %d options, got %d", 2, len(opts))
+	}
+	n, _, err := selector.Select(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual("v2.0.0", n.Version()) {
+		t.Errorf("expect %v, got %v", "v2.0.0", n.Version())
+	}
+	if picked != n {
+		t.Errorf("expect %v, got %v", n, picked)
+	}
+	// filters carried by ctx are applied along with the explicit ones
+	_, _, err = selector.Select(ctx, WithFilter(mockFilter("v1.0.0")))
+	if !errors.Is(ErrNoAvailable, err) {
+		t.Errorf("expect %v, got %v", ErrNoAvailable, err)
+	}
+}
+
+func TestDefault_Draining(t *testing.T) {
+	builder := DefaultBuilder{
+		Node:     &mockWeightedNodeBuilder{},
+		Balancer: &mockBalancerBuilder{},
+	}
+	selector := builder.Build()
+	selector.Apply([]Node{
+		NewNode("http", "127.0.0.1:8080", &registry.ServiceInstance{Metadata: map[string]string{registry.StatusKey: registry.StatusDraining}}),
+		NewNode("http", "127.0.0.1:9090", &registry.ServiceInstance{}),
+	})
+	for i := 0; i < 10; i++ {
+		n, _, err := selector.Select(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Address() != "127.0.0.1:9090" {
+			t.Errorf("expect %v, got %v", "127.0.0.1:9090", n.Address())
+		}
+	}
+	// all nodes are draining
+	selector.Apply([]Node{
+		NewNode("http", "127.0.0.1:8080", &registry.ServiceInstance{Metadata: map[string]string{registry.StatusKey: registry.StatusDraining}}),
+	})
+	if _, _, err := selector.Select(context.Background()); err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+}
@@ -0,0 +1,61 @@
+package subset
+
+import (
+	"hash/fnv"
+	"os"
+	"sort"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var hostname, _ = os.Hostname()
+
+// Subset returns a deterministic subset of the instances with the size for the client key,
+// the instances are ranked by rendezvous hashing, so every client picks a stable subset,
+// the subsets spread evenly over the instances, and a membership change only replaces
+// the instances which joined or left.
+// The key is usually the app ID of the client, an empty key falls back to the hostname.
+func Subset(key string, size int, instances []*registry.ServiceInstance) []*registry.ServiceInstance {
+	if size <= 0 || len(instances) <= size {
+		return instances
+	}
+	if key == "" {
+		key = hostname
+	}
+	type scored struct {
+		ins   *registry.ServiceInstance
+		score uint64
+	}
+	seed := hash(key)
+	ranked := make([]scored, 0, len(instances))
+	for _, ins := range instances {
+		ranked = append(ranked, scored{ins: ins, score: mix(seed ^ hash(ins.ID))})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score == ranked[j].score {
+			return ranked[i].ins.ID < ranked[j].ins.ID
+		}
+		return ranked[i].score > ranked[j].score
+	})
+	subset := make([]*registry.ServiceInstance, 0, size)
+	for _, s := range ranked[:size] {
+		subset = append(subset, s.ins)
+	}
+	return subset
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix is the splitmix64 finalizer, which spreads the similar hashes.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
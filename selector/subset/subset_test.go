@@ -0,0 +1,89 @@
+package subset
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+func instances(n int) []*registry.ServiceInstance {
+	ins := make([]*registry.ServiceInstance, 0, n)
+	for i := 0; i < n; i++ {
+		ins = append(ins, &registry.ServiceInstance{ID: "node-" + strconv.Itoa(i)})
+	}
+	return ins
+}
+
+func ids(ins []*registry.ServiceInstance) map[string]bool {
+	m := make(map[string]bool, len(ins))
+	for _, in := range ins {
+		m[in.ID] = true
+	}
+	return m
+}
+
+func TestSubset(t *testing.T) {
+	all := instances(100)
+	if got := Subset("client", 0, all); len(got) != 100 {
+		t.Errorf("expect %v, got %v", 100, len(got))
+	}
+	if got := Subset("client", 200, all); len(got) != 100 {
+		t.Errorf("expect %v, got %v", 100, len(got))
+	}
+	a := Subset("client", 10, all)
+	if len(a) != 10 {
+		t.Fatalf("expect %v, got %v", 10, len(a))
+	}
+	// deterministic regardless of the order
+	reversed := make([]*registry.ServiceInstance, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		reversed = append(reversed, all[i])
+	}
+	b := ids(Subset("client", 10, reversed))
+	for _, in := range a {
+		if !b[in.ID] {
+			t.Errorf("expect %v in subset", in.ID)
+		}
+	}
+}
+
+func TestSubset_Membership(t *testing.T) {
+	all := instances(100)
+	before := ids(Subset("client", 10, all))
+	// remove a node out of the subset, and add a new node
+	var removed int
+	for i, in := range all {
+		if !before[in.ID] {
+			removed = i
+			break
+		}
+	}
+	changed := append(append(append([]*registry.ServiceInstance{}, all[:removed]...), all[removed+1:]...), &registry.ServiceInstance{ID: "node-new"})
+	after := ids(Subset("client", 10, changed))
+	diff := 0
+	for id := range after {
+		if !before[id] {
+			diff++
+		}
+	}
+	if diff > 1 {
+		t.Errorf("expect at most %v changes, got %v", 1, diff)
+	}
+}
+
+func TestSubset_Spread(t *testing.T) {
+	all := instances(20)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		for _, in := range Subset("client-"+strconv.Itoa(i), 5, all) {
+			counts[in.ID]++
+		}
+	}
+	// 1000 * 5 / 20 = 250 per node
+	for id, c := range counts {
+		if c < 150 || c > 350 {
+			t.Errorf("expect about %v connections to %s, got %v", 250, id, c)
+		}
+	}
+}
@@ -5,10 +5,12 @@ import (
 
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/leastrequest"
 	"github.com/go-kratos/kratos/v2/selector/p2c"
 	"github.com/go-kratos/kratos/v2/selector/random"
 	"github.com/go-kratos/kratos/v2/selector/wrr"
 	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/grpc/resolver/discovery"
 
 	gBalancer "google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/balancer/base"
@@ -27,6 +29,7 @@ func init() {
 	SetGlobalBalancer(random.Name, random.NewBuilder())
 	SetGlobalBalancer(wrr.Name, wrr.NewBuilder())
 	SetGlobalBalancer(p2c.Name, p2c.NewBuilder())
+	SetGlobalBalancer(leastrequest.Name, leastrequest.NewBuilder())
 }
 
 // SetGlobalBalancer set grpc balancer with scheme.
@@ -34,17 +37,32 @@ func SetGlobalBalancer(scheme string, builder selector.Builder) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	b := base.NewBalancerBuilder(
-		scheme,
-		&Builder{builder: builder},
+	gBalancer.Register(&balancerBuilder{name: scheme, builder: builder})
+}
+
+// balancerBuilder builds a balancer for each ClientConn, whose pickers share one selector,
+// so that the state of the nodes, e.g. the in-flight requests, survives rebuilding the pickers,
+// and it's never shared by the ClientConns.
+type balancerBuilder struct {
+	name    string
+	builder selector.Builder
+}
+
+func (b *balancerBuilder) Build(cc gBalancer.ClientConn, opts gBalancer.BuildOptions) gBalancer.Balancer {
+	return base.NewBalancerBuilder(
+		b.name,
+		&Builder{selector: b.builder.Build()},
 		base.Config{HealthCheck: true},
-	)
-	gBalancer.Register(b)
+	).Build(cc, opts)
+}
+
+func (b *balancerBuilder) Name() string {
+	return b.name
 }
 
-// Builder is grpc balancer builder.
+// Builder is grpc picker builder of a ClientConn.
 type Builder struct {
-	builder selector.Builder
+	selector selector.Selector
 }
 
 // Build creates a grpc Picker.
@@ -57,16 +75,15 @@ func (b *Builder) Build(info base.PickerBuildInfo) gBalancer.Picker {
 	nodes := make([]selector.Node, 0)
 	for conn, info := range info.ReadySCs {
 		ins, _ := info.Address.Attributes.Value("rawServiceInstance").(*registry.ServiceInstance)
-		nodes = append(nodes, &grpcNode{
+		n := &grpcNode{
 			Node:    selector.NewNode("grpc", info.Address.Addr, ins),
 			subConn: conn,
-		})
-	}
-	p := &Picker{
-		selector: b.builder.Build(),
+		}
+		n.latest, _ = discovery.Node(info.Address)
+		nodes = append(nodes, n)
 	}
-	p.selector.Apply(nodes)
-	return p
+	b.selector.Apply(nodes)
+	return &Picker{selector: b.selector}
 }
 
 // Picker is a grpc picker.
@@ -116,4 +133,36 @@ func (t Trailer) Get(k string) string {
 type grpcNode struct {
 	selector.Node
 	subConn gBalancer.SubConn
+	// latest loads the node with the latest metadata, if resolved by discovery
+	latest func() selector.Node
+}
+
+func (n *grpcNode) node() selector.Node {
+	if n.latest != nil {
+		return n.latest()
+	}
+	return n.Node
+}
+
+// ID is the id of the service instance of the node.
+func (n *grpcNode) ID() string {
+	if i, ok := n.Node.(interface{ ID() string }); ok {
+		return i.ID()
+	}
+	return ""
+}
+
+// InitialWeight is the latest initial weight of the node.
+func (n *grpcNode) InitialWeight() *int64 {
+	return n.node().InitialWeight()
+}
+
+// Version is the latest version of the node.
+func (n *grpcNode) Version() string {
+	return n.node().Version()
+}
+
+// Metadata is the latest metadata of the node.
+func (n *grpcNode) Metadata() map[string]string {
+	return n.node().Metadata()
 }
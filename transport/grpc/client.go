@@ -4,8 +4,12 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
+	ireply "github.com/go-kratos/kratos/v2/internal/reply"
+	istream "github.com/go-kratos/kratos/v2/internal/stream"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
@@ -13,6 +17,7 @@ import (
 	"github.com/go-kratos/kratos/v2/selector/wrr"
 	"github.com/go-kratos/kratos/v2/transport"
 	"github.com/go-kratos/kratos/v2/transport/grpc/resolver/discovery"
+	"github.com/go-kratos/kratos/v2/transport/healthcheck"
 
 	// init resolver
 	_ "github.com/go-kratos/kratos/v2/transport/grpc/resolver/direct"
@@ -47,6 +52,14 @@ func WithMiddleware(m ...middleware.Middleware) ClientOption {
 	}
 }
 
+// WithStreamMiddleware with client stream middleware,
+// which is applied to every message sent or received on a streaming RPC.
+func WithStreamMiddleware(m ...middleware.Middleware) ClientOption {
+	return func(o *clientOptions) {
+		o.streamMiddleware = m
+	}
+}
+
 // WithDiscovery with client discovery.
 func WithDiscovery(d registry.Discovery) ClientOption {
 	return func(o *clientOptions) {
@@ -68,6 +81,13 @@ func WithUnaryInterceptor(in ...grpc.UnaryClientInterceptor) ClientOption {
 	}
 }
 
+// WithStreamInterceptor returns a DialOption that specifies the interceptor for streaming RPCs.
+func WithStreamInterceptor(in ...grpc.StreamClientInterceptor) ClientOption {
+	return func(o *clientOptions) {
+		o.streamInts = in
+	}
+}
+
 // WithOptions with gRPC options.
 func WithOptions(opts ...grpc.DialOption) ClientOption {
 	return func(o *clientOptions) {
@@ -89,6 +109,24 @@ func WithFilter(filters ...selector.Filter) ClientOption {
 	}
 }
 
+// WithHealthCheck with active health checking of the discovered nodes by grpc_health_v1.
+func WithHealthCheck(opts ...healthcheck.Option) ClientOption {
+	return func(o *clientOptions) {
+		o.healthCheck = true
+		o.healthOpts = opts
+	}
+}
+
+// WithSubset with deterministic subsetting of the discovered nodes,
+// the client only connects to a stable subset of the nodes with the size,
+// which is keyed by the client key, usually the app ID.
+func WithSubset(key string, size int) ClientOption {
+	return func(o *clientOptions) {
+		o.subsetKey = key
+		o.subsetSize = size
+	}
+}
+
 // WithLogger with logger
 // Deprecated: use global logger instead.
 func WithLogger(log log.Logger) ClientOption {
@@ -97,15 +135,21 @@ func WithLogger(log log.Logger) ClientOption {
 
 // clientOptions is gRPC Client
 type clientOptions struct {
-	endpoint     string
-	tlsConf      *tls.Config
-	timeout      time.Duration
-	discovery    registry.Discovery
-	middleware   []middleware.Middleware
-	ints         []grpc.UnaryClientInterceptor
-	grpcOpts     []grpc.DialOption
-	balancerName string
-	filters      []selector.Filter
+	endpoint         string
+	tlsConf          *tls.Config
+	timeout          time.Duration
+	discovery        registry.Discovery
+	middleware       []middleware.Middleware
+	streamMiddleware []middleware.Middleware
+	ints             []grpc.UnaryClientInterceptor
+	streamInts       []grpc.StreamClientInterceptor
+	grpcOpts         []grpc.DialOption
+	balancerName     string
+	filters          []selector.Filter
+	healthCheck      bool
+	healthOpts       []healthcheck.Option
+	subsetKey        string
+	subsetSize       int
 }
 
 // Dial returns a GRPC connection.
@@ -132,16 +176,32 @@ func dial(ctx context.Context, insecure bool, opts ...ClientOption) (*grpc.Clien
 	if len(options.ints) > 0 {
 		ints = append(ints, options.ints...)
 	}
+	streamInts := []grpc.StreamClientInterceptor{
+		streamClientInterceptor(options.middleware, options.streamMiddleware, options.filters),
+	}
+	if len(options.streamInts) > 0 {
+		streamInts = append(streamInts, options.streamInts...)
+	}
 	grpcOpts := []grpc.DialOption{
 		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig": [{"%s":{}}]}`, options.balancerName)),
 		grpc.WithChainUnaryInterceptor(ints...),
+		grpc.WithChainStreamInterceptor(streamInts...),
 	}
 	if options.discovery != nil {
+		dopts := []discovery.Option{discovery.WithInsecure(insecure)}
+		if options.healthCheck {
+			prober := newHealthProber(insecure, options.tlsConf)
+			healthOpts := append([]healthcheck.Option{healthcheck.WithRelease(prober.Release)}, options.healthOpts...)
+			dopts = append(dopts, discovery.WithHealthCheck(prober.Probe, healthOpts...))
+		}
+		if options.subsetSize > 0 {
+			dopts = append(dopts, discovery.WithSubset(options.subsetKey, options.subsetSize))
+		}
 		grpcOpts = append(grpcOpts,
 			grpc.WithResolvers(
 				discovery.NewBuilder(
 					options.discovery,
-					discovery.WithInsecure(insecure),
+					dopts...,
 				)))
 	}
 	if insecure {
@@ -170,23 +230,129 @@ func unaryClientInterceptor(ms []middleware.Middleware, timeout time.Duration, f
 			defer cancel()
 		}
 		h := func(ctx context.Context, req interface{}) (interface{}, error) {
-			if tr, ok := transport.FromClientContext(ctx); ok {
-				header := tr.RequestHeader()
-				keys := header.Keys()
-				keyvals := make([]string, 0, len(keys))
-				for _, k := range keys {
-					keyvals = append(keyvals, k, header.Get(k))
-				}
-				ctx = grpcmd.AppendToOutgoingContext(ctx, keyvals...)
+			out := reply
+			if ireply.Isolated(ctx) {
+				out = ireply.New(reply)
 			}
-			return reply, invoker(ctx, method, req, reply, cc, opts...)
+			return out, invoker(outgoingContext(ctx), method, req, out, cc, opts...)
 		}
 		if len(ms) > 0 {
 			h = middleware.Chain(ms...)(h)
 		}
 		var p selector.Peer
 		ctx = selector.NewPeerContext(ctx, &p)
-		_, err := h(ctx, req)
+		out, err := h(ctx, req)
+		if err == nil && out != nil {
+			// the reply returned by the middleware may not be the caller's one.
+			err = ireply.Copy(reply, out)
+		}
+		return err
+	}
+}
+
+// streamClientInterceptor runs the client middleware for the whole lifetime of the stream,
+// which ends once RecvMsg returns an error or io.EOF, or the ctx is done,
+// and the stream middleware for every message sent or received on the stream.
+func streamClientInterceptor(ms []middleware.Middleware, sms []middleware.Middleware, filters []selector.Filter) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = transport.NewClientContext(ctx, &Transport{
+			endpoint:  cc.Target(),
+			operation: method,
+			reqHeader: headerCarrier{},
+			filters:   filters,
+		})
+		var m middleware.Middleware
+		if len(ms) > 0 {
+			m = middleware.Chain(ms...)
+		}
+		var p selector.Peer
+		ctx = selector.NewPeerContext(ctx, &p)
+		cs, err := istream.Open(ctx, m, nil, func(ctx context.Context) (interface{}, <-chan error, error) {
+			stream, err := streamer(outgoingContext(ctx), desc, cc, method, opts...)
+			if err != nil {
+				return nil, nil, err
+			}
+			ws := newWrappedClientStream(ctx, stream, desc.ServerStreams, sms...)
+			return ws, ws.done, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return cs.(grpc.ClientStream), nil
+	}
+}
+
+// wrappedClientStream runs the stream middleware for every message,
+// and reports the end of the stream.
+type wrappedClientStream struct {
+	grpc.ClientStream
+	ctx           context.Context
+	middleware    middleware.Middleware
+	serverStreams bool
+	done          chan error
+	once          sync.Once
+}
+
+func newWrappedClientStream(ctx context.Context, stream grpc.ClientStream, serverStreams bool, m ...middleware.Middleware) *wrappedClientStream {
+	w := &wrappedClientStream{
+		ClientStream:  stream,
+		ctx:           ctx,
+		serverStreams: serverStreams,
+		done:          make(chan error, 1),
+	}
+	if len(m) > 0 {
+		w.middleware = middleware.Chain(m...)
+	}
+	return w
+}
+
+// SendMsg runs the stream middleware before sending the message.
+func (w *wrappedClientStream) SendMsg(m interface{}) error {
+	if w.middleware == nil {
+		return w.ClientStream.SendMsg(m)
+	}
+	_, err := w.middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, w.ClientStream.SendMsg(req)
+	})(w.ctx, m)
+	return err
+}
+
+// RecvMsg runs the stream middleware after the message has been received.
+func (w *wrappedClientStream) RecvMsg(m interface{}) error {
+	err := w.ClientStream.RecvMsg(m)
+	// the stream without server streaming ends with the only reply.
+	if err != nil || !w.serverStreams {
+		w.finish(err)
+	}
+	if err != nil || w.middleware == nil {
 		return err
 	}
+	_, err = w.middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})(w.ctx, m)
+	return err
+}
+
+func (w *wrappedClientStream) finish(err error) {
+	w.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		w.done <- err
+	})
+}
+
+// outgoingContext appends the client transport request header to the outgoing metadata.
+func outgoingContext(ctx context.Context) context.Context {
+	tr, ok := transport.FromClientContext(ctx)
+	if !ok {
+		return ctx
+	}
+	header := tr.RequestHeader()
+	keys := header.Keys()
+	keyvals := make([]string, 0, len(keys))
+	for _, k := range keys {
+		keyvals = append(keyvals, k, header.Get(k))
+	}
+	return grpcmd.AppendToOutgoingContext(ctx, keyvals...)
 }
@@ -3,13 +3,17 @@ package grpc
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"io"
 	"reflect"
 	"testing"
 	"time"
 
+	istream "github.com/go-kratos/kratos/v2/internal/stream"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
 	"google.golang.org/grpc"
+	grpcinsecure "google.golang.org/grpc/credentials/insecure"
 )
 
 func TestWithEndpoint(t *testing.T) {
@@ -69,6 +73,14 @@ func TestWithTLSConfig(t *testing.T) {
 	}
 }
 
+func TestWithSubset(t *testing.T) {
+	o := &clientOptions{}
+	WithSubset("client", 10)(o)
+	if o.subsetKey != "client" || o.subsetSize != 10 {
+		t.Errorf("expect %v %v but got %v %v", "client", 10, o.subsetKey, o.subsetSize)
+	}
+}
+
 func EmptyMiddleware() middleware.Middleware {
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
@@ -142,3 +154,118 @@ func TestDialConn(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestWithStreamMiddleware(t *testing.T) {
+	o := &clientOptions{}
+	v := []middleware.Middleware{
+		func(middleware.Handler) middleware.Handler { return nil },
+	}
+	WithStreamMiddleware(v...)(o)
+	if !reflect.DeepEqual(v, o.streamMiddleware) {
+		t.Errorf("expect %v but got %v", v, o.streamMiddleware)
+	}
+}
+
+func TestWithStreamInterceptor(t *testing.T) {
+	o := &clientOptions{}
+	v := []grpc.StreamClientInterceptor{
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return nil, nil
+		},
+	}
+	WithStreamInterceptor(v...)(o)
+	if !reflect.DeepEqual(len(v), len(o.streamInts)) {
+		t.Errorf("expect %v but got %v", v, o.streamInts)
+	}
+}
+
+type mockClientStream struct {
+	grpc.ClientStream
+	recv chan error
+}
+
+func (s *mockClientStream) RecvMsg(m interface{}) error {
+	return <-s.recv
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	cc, err := grpc.Dial("127.0.0.1:0", grpc.WithTransportCredentials(grpcinsecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	var (
+		ended   = make(chan error, 1)
+		attempt = make(chan error, 1)
+		sctx    context.Context
+		stream  = &mockClientStream{recv: make(chan error, 1)}
+	)
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			// the ctx canceled on return, such as the per-attempt timeout of retries.
+			actx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			go func() {
+				// a hedged attempt
+				time.Sleep(5 * time.Millisecond)
+				_, err := handler(actx, req)
+				attempt <- err
+			}()
+			_, err := handler(actx, req)
+			ended <- err
+			return nil, err
+		}
+	}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		sctx = ctx
+		return stream, nil
+	}
+	cs, err := streamClientInterceptor([]middleware.Middleware{m}, nil, nil)(context.Background(), &grpc.StreamDesc{ServerStreams: true}, cc, "/test.Service/Watch", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if sctx.Err() != nil {
+		t.Errorf("expect the stream alive, got %v", sctx.Err())
+	}
+	if err = <-attempt; !errors.Is(err, istream.ErrOpened) {
+		t.Errorf("expect %v, got %v", istream.ErrOpened, err)
+	}
+	select {
+	case err = <-ended:
+		t.Fatalf("expect the middleware to wait for the end of the stream, got %v", err)
+	default:
+	}
+	stream.recv <- io.EOF
+	if err = cs.RecvMsg(nil); err != io.EOF {
+		t.Errorf("expect %v, got %v", io.EOF, err)
+	}
+	select {
+	case err = <-ended:
+		if err != nil {
+			t.Errorf("expect %v, got %v", nil, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect the middleware to return once the stream ends")
+	}
+}
+
+func TestStreamClientInterceptor_NotOpened(t *testing.T) {
+	cc, err := grpc.Dial("127.0.0.1:0", grpc.WithTransportCredentials(grpcinsecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		}
+	}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		t.Error("expect the stream not opened")
+		return nil, nil
+	}
+	if _, err = streamClientInterceptor([]middleware.Middleware{m}, nil, nil)(context.Background(), &grpc.StreamDesc{}, cc, "/test.Service/Watch", streamer); !errors.Is(err, istream.ErrNotOpened) {
+		t.Errorf("expect %v, got %v", istream.ErrNotOpened, err)
+	}
+}
@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/internal/endpoint"
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpcinsecure "google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthProber checks the instances by grpc_health_v1,
+// it keeps one connection per target until the instance is released.
+type healthProber struct {
+	insecure bool
+	creds    credentials.TransportCredentials
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func newHealthProber(insecure bool, tlsConf *tls.Config) *healthProber {
+	creds := grpcinsecure.NewCredentials()
+	if !insecure {
+		creds = credentials.NewTLS(tlsConf)
+	}
+	return &healthProber{
+		insecure: insecure,
+		creds:    creds,
+		conns:    make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Probe checks the health status of the instance.
+func (p *healthProber) Probe(ctx context.Context, ins *registry.ServiceInstance) error {
+	addr, err := endpoint.ParseEndpoint(ins.Endpoints, endpoint.Scheme("grpc", !p.insecure))
+	if err != nil {
+		return err
+	}
+	if addr == "" {
+		// the instance is skipped by the resolver
+		return nil
+	}
+	conn, err := p.conn(addr)
+	if err != nil {
+		return err
+	}
+	reply, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if reply.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health status: %s", reply.Status)
+	}
+	return nil
+}
+
+// Release closes the connection to the instance.
+func (p *healthProber) Release(ins *registry.ServiceInstance) {
+	addr, err := endpoint.ParseEndpoint(ins.Endpoints, endpoint.Scheme("grpc", !p.insecure))
+	if err != nil || addr == "" {
+		return
+	}
+	p.mu.Lock()
+	conn, ok := p.conns[addr]
+	delete(p.conns, addr)
+	p.mu.Unlock()
+	if ok {
+		_ = conn.Close()
+	}
+}
+
+func (p *healthProber) conn(addr string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, ok := p.conns[addr]; ok {
+		return conn, nil
+	}
+	// the connection is established in the background, and reconnects on failures.
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(p.creds))
+	if err != nil {
+		return nil, err
+	}
+	p.conns[addr] = conn
+	return conn, nil
+}
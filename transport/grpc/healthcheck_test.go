@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/registry"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestWithHealthCheck(t *testing.T) {
+	o := &clientOptions{}
+	WithHealthCheck()(o)
+	if !o.healthCheck {
+		t.Errorf("expect %v but got %v", true, o.healthCheck)
+	}
+}
+
+func TestHealthProber(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := health.NewServer()
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	p := newHealthProber(true, nil)
+	ins := &registry.ServiceInstance{Endpoints: []string{"grpc://" + lis.Addr().String()}}
+	if err = p.Probe(context.Background(), ins); err != nil {
+		t.Errorf("expect %v but got %v", nil, err)
+	}
+	hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	if err = p.Probe(context.Background(), ins); err == nil {
+		t.Errorf("expect error but got %v", err)
+	}
+	if len(p.conns) != 1 {
+		t.Errorf("expect %v but got %v", 1, len(p.conns))
+	}
+	p.Release(ins)
+	if len(p.conns) != 0 {
+		t.Errorf("expect %v but got %v", 0, len(p.conns))
+	}
+}
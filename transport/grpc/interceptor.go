@@ -2,8 +2,10 @@ package grpc
 
 import (
 	"context"
+	"sync/atomic"
 
 	ic "github.com/go-kratos/kratos/v2/internal/context"
+	"github.com/go-kratos/kratos/v2/internal/deadline"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
 	"google.golang.org/grpc"
@@ -13,6 +15,8 @@ import (
 // unaryServerInterceptor is a gRPC unary server interceptor
 func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt64(&s.inflight, 1)
+		defer atomic.AddInt64(&s.inflight, -1)
 		ctx, cancel := ic.Merge(ctx, s.baseCtx)
 		defer cancel()
 		md, _ := grpcmd.FromIncomingContext(ctx)
@@ -23,10 +27,18 @@ func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 			reqHeader:   headerCarrier(md),
 			replyHeader: headerCarrier(replyHeader),
 		})
-		if s.timeout > 0 {
-			ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		timeout := s.timeout
+		if t, ok := s.routeTimeouts[info.FullMethod]; ok {
+			timeout = t
+		}
+		if timeout > 0 {
+			// the deadline sent by the client is kept if it's earlier.
+			ctx, cancel = context.WithTimeout(ctx, timeout)
 			defer cancel()
 		}
+		if err := deadline.Check(ctx, s.minDeadline); err != nil {
+			return nil, err
+		}
 		h := func(ctx context.Context, req interface{}) (interface{}, error) {
 			return handler(ctx, req)
 		}
@@ -42,25 +54,62 @@ func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 }
 
 // wrappedStream is rewrite grpc stream's context
+// and runs the stream middleware for every message.
 type wrappedStream struct {
 	grpc.ServerStream
-	ctx context.Context
+	ctx        context.Context
+	middleware middleware.Middleware
 }
 
-func NewWrappedStream(ctx context.Context, stream grpc.ServerStream) grpc.ServerStream {
-	return &wrappedStream{
+// NewWrappedStream returns a grpc.ServerStream which carries ctx,
+// the given middleware is applied to each message received or sent on the stream.
+func NewWrappedStream(ctx context.Context, stream grpc.ServerStream, m ...middleware.Middleware) grpc.ServerStream {
+	ws := &wrappedStream{
 		ServerStream: stream,
 		ctx:          ctx,
 	}
+	if len(m) > 0 {
+		ws.middleware = middleware.Chain(m...)
+	}
+	return ws
 }
 
 func (w *wrappedStream) Context() context.Context {
 	return w.ctx
 }
 
-// streamServerInterceptor is a gRPC stream server interceptor
+// SendMsg runs the stream middleware before sending the message.
+func (w *wrappedStream) SendMsg(m interface{}) error {
+	if w.middleware == nil {
+		return w.ServerStream.SendMsg(m)
+	}
+	_, err := w.middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, w.ServerStream.SendMsg(req)
+	})(w.ctx, m)
+	return err
+}
+
+// RecvMsg runs the stream middleware after the message has been received.
+func (w *wrappedStream) RecvMsg(m interface{}) error {
+	if err := w.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if w.middleware == nil {
+		return nil
+	}
+	_, err := w.middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})(w.ctx, m)
+	return err
+}
+
+// streamServerInterceptor is a gRPC stream server interceptor.
+// The server middleware is run once for the whole stream with a nil request,
+// so it observes the start and the end of the stream.
 func (s *Server) streamServerInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		atomic.AddInt64(&s.inflight, 1)
+		defer atomic.AddInt64(&s.inflight, -1)
 		ctx, cancel := ic.Merge(ss.Context(), s.baseCtx)
 		defer cancel()
 		md, _ := grpcmd.FromIncomingContext(ctx)
@@ -71,13 +120,25 @@ func (s *Server) streamServerInterceptor() grpc.StreamServerInterceptor {
 			reqHeader:   headerCarrier(md),
 			replyHeader: headerCarrier(replyHeader),
 		})
-
-		ws := NewWrappedStream(ctx, ss)
-
-		err := handler(srv, ws)
-		if len(replyHeader) > 0 {
-			_ = grpc.SetHeader(ctx, replyHeader)
+		// the server timeout is not applied to the streams, unless the route timeout is set.
+		if timeout, ok := s.routeTimeouts[info.FullMethod]; ok && timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if err := deadline.Check(ctx, s.minDeadline); err != nil {
+			return err
+		}
+		h := func(ctx context.Context, req interface{}) (interface{}, error) {
+			// the header must be set before the first message is sent
+			if len(replyHeader) > 0 {
+				_ = ss.SetHeader(replyHeader)
+			}
+			return nil, handler(srv, NewWrappedStream(ctx, ss, s.streamMiddleware...))
+		}
+		if len(s.middleware) > 0 {
+			h = middleware.Chain(s.middleware...)(h)
 		}
+		_, err := h(ctx, nil)
 		return err
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/transport/healthcheck"
 
 	"google.golang.org/grpc/resolver"
 )
@@ -37,11 +38,33 @@ func DisableDebugLog() Option {
 	}
 }
 
+// WithHealthCheck with active health checking, the unhealthy instances
+// are filtered out before they are updated to the balancer.
+func WithHealthCheck(probe healthcheck.Prober, opts ...healthcheck.Option) Option {
+	return func(b *builder) {
+		b.probe = probe
+		b.healthOpts = opts
+	}
+}
+
+// WithSubset with deterministic subsetting, only a subset of the instances
+// with the size for the client key is updated to the balancer.
+func WithSubset(key string, size int) Option {
+	return func(b *builder) {
+		b.subsetKey = key
+		b.subsetSize = size
+	}
+}
+
 type builder struct {
 	discoverer       registry.Discovery
 	timeout          time.Duration
 	insecure         bool
 	debugLogDisabled bool
+	probe            healthcheck.Prober
+	healthOpts       []healthcheck.Option
+	subsetKey        string
+	subsetSize       int
 }
 
 // NewBuilder creates a builder which is used to factory registry resolvers.
@@ -85,6 +108,11 @@ func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts res
 		cancel:           cancel,
 		insecure:         b.insecure,
 		debugLogDisabled: b.debugLogDisabled,
+		subsetKey:        b.subsetKey,
+		subsetSize:       b.subsetSize,
+	}
+	if b.probe != nil {
+		r.checker = healthcheck.New(b.probe, r.update, b.healthOpts...)
 	}
 	go r.watch()
 	return r, nil
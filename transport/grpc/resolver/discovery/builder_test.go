@@ -28,6 +28,22 @@ func TestWithTimeout(t *testing.T) {
 	}
 }
 
+func TestWithHealthCheck(t *testing.T) {
+	o := &builder{}
+	WithHealthCheck(func(context.Context, *registry.ServiceInstance) error { return nil })(o)
+	if o.probe == nil {
+		t.Errorf("expected probe to be set")
+	}
+}
+
+func TestWithSubset(t *testing.T) {
+	o := &builder{}
+	WithSubset("client", 10)(o)
+	if o.subsetKey != "client" || o.subsetSize != 10 {
+		t.Errorf("expected %v %v, got %v %v", "client", 10, o.subsetKey, o.subsetSize)
+	}
+}
+
 type mockDiscovery struct{}
 
 func (m *mockDiscovery) GetService(ctx context.Context, serviceName string) ([]*registry.ServiceInstance, error) {
@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/subset"
+	"github.com/go-kratos/kratos/v2/transport/healthcheck"
 
 	"google.golang.org/grpc/attributes"
 	"google.golang.org/grpc/resolver"
@@ -23,6 +28,34 @@ type discoveryResolver struct {
 
 	insecure         bool
 	debugLogDisabled bool
+	checker          *healthcheck.Checker
+	subsetKey        string
+	subsetSize       int
+
+	mu    sync.Mutex
+	nodes map[string]*node
+}
+
+type nodeKey struct{}
+
+// node keeps the address of an endpoint stable while only the metadata of its instance changes,
+// as gRPC reconnects to an address whose attributes changed, so the attributes are of the instance
+// when the endpoint is first resolved, and the latest node is loaded by Node.
+type node struct {
+	id   string
+	addr resolver.Address
+	// latest selector.Node
+	latest atomic.Value
+}
+
+// Node returns the function which loads the latest selector node of the address,
+// it's kept up to date with the metadata changes of the instance, such as the weight.
+func Node(addr resolver.Address) (func() selector.Node, bool) {
+	n, ok := addr.Attributes.Value(nodeKey{}).(*node)
+	if !ok {
+		return nil, false
+	}
+	return func() selector.Node { return n.latest.Load().(selector.Node) }, true
 }
 
 func (r *discoveryResolver) watch() {
@@ -41,13 +74,23 @@ func (r *discoveryResolver) watch() {
 			time.Sleep(time.Second)
 			continue
 		}
+		if r.checker != nil {
+			r.checker.Update(ins)
+			continue
+		}
 		r.update(ins)
 	}
 }
 
+// update updates the subset of the instances, which are the healthy ones
+// if the health check is enabled, so the subset is refilled when any is unhealthy.
 func (r *discoveryResolver) update(ins []*registry.ServiceInstance) {
+	ins = subset.Subset(r.subsetKey, r.subsetSize, ins)
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	addrs := make([]resolver.Address, 0)
 	endpoints := make(map[string]struct{})
+	nodes := make(map[string]*node, len(ins))
 	for _, in := range ins {
 		endpoint, err := endpoint.ParseEndpoint(in.Endpoints, endpoint.Scheme("grpc", !r.insecure))
 		if err != nil {
@@ -62,18 +105,25 @@ func (r *discoveryResolver) update(ins []*registry.ServiceInstance) {
 			continue
 		}
 		endpoints[endpoint] = struct{}{}
-		addr := resolver.Address{
-			ServerName: in.Name,
-			Attributes: parseAttributes(in.Metadata),
-			Addr:       endpoint,
+		n, ok := r.nodes[endpoint]
+		if !ok || n.id != in.ID || n.addr.ServerName != in.Name {
+			n = &node{id: in.ID}
+			n.addr = resolver.Address{
+				ServerName: in.Name,
+				Attributes: parseAttributes(in.Metadata),
+				Addr:       endpoint,
+			}
+			n.addr.Attributes = n.addr.Attributes.WithValue("rawServiceInstance", in).WithValue(nodeKey{}, n)
 		}
-		addr.Attributes = addr.Attributes.WithValue("rawServiceInstance", in)
-		addrs = append(addrs, addr)
+		n.latest.Store(selector.NewNode("grpc", endpoint, in))
+		nodes[endpoint] = n
+		addrs = append(addrs, n.addr)
 	}
 	if len(addrs) == 0 {
 		log.Warnf("[resolver] Zero endpoint found,refused to write, instances: %v", ins)
 		return
 	}
+	r.nodes = nodes
 	err := r.cc.UpdateState(resolver.State{Addresses: addrs})
 	if err != nil {
 		log.Errorf("[resolver] failed to update state: %s", err)
@@ -87,6 +137,9 @@ func (r *discoveryResolver) update(ins []*registry.ServiceInstance) {
 
 func (r *discoveryResolver) Close() {
 	r.cancel()
+	if r.checker != nil {
+		r.checker.Stop()
+	}
 	err := r.w.Stop()
 	if err != nil {
 		log.Errorf("[resolver] failed to watch top: %s", err)
@@ -114,3 +114,44 @@ func TestParseAttributes(t *testing.T) {
 		t.Errorf("expect nil, got %v", x.Value("notfound"))
 	}
 }
+
+type stateClientConn struct {
+	resolver.ClientConn
+	state resolver.State
+}
+
+func (c *stateClientConn) UpdateState(s resolver.State) error {
+	c.state = s
+	return nil
+}
+
+func TestUpdate_KeepAddress(t *testing.T) {
+	cc := &stateClientConn{}
+	r := &discoveryResolver{cc: cc, insecure: true, debugLogDisabled: true}
+	r.update([]*registry.ServiceInstance{
+		{ID: "1", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000"}, Metadata: map[string]string{"weight": "10"}},
+	})
+	before := cc.state.Addresses[0]
+	r.update([]*registry.ServiceInstance{
+		{ID: "1", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000"}, Metadata: map[string]string{"weight": "50"}},
+	})
+	after := cc.state.Addresses[0]
+	// the address is kept, so the connection is kept
+	if !before.Equal(after) {
+		t.Errorf("expect %v, got %v", before, after)
+	}
+	latest, ok := Node(after)
+	if !ok {
+		t.Fatal("expect node loader")
+	}
+	if w := latest().InitialWeight(); w == nil || *w != 50 {
+		t.Errorf("expect %v, got %v", 50, w)
+	}
+	// a new instance on the same endpoint
+	r.update([]*registry.ServiceInstance{
+		{ID: "2", Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000"}},
+	})
+	if before.Equal(cc.state.Addresses[0]) {
+		t.Errorf("expect a new address, got %v", cc.state.Addresses[0])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"net"
 	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
@@ -27,6 +28,7 @@ import (
 var (
 	_ transport.Server     = (*Server)(nil)
 	_ transport.Endpointer = (*Server)(nil)
+	_ transport.Drainer    = (*Server)(nil)
 )
 
 // ServerOption is gRPC server option.
@@ -53,6 +55,36 @@ func Timeout(timeout time.Duration) ServerOption {
 	}
 }
 
+// RouteTimeout with the timeout of the operation, it overrides the server timeout.
+func RouteTimeout(operation string, timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		if s.routeTimeouts == nil {
+			s.routeTimeouts = make(map[string]time.Duration)
+		}
+		s.routeTimeouts[operation] = timeout
+	}
+}
+
+// RouteTimeouts with the timeouts of the operations, e.g. transport.RouteTimeouts loaded from config.
+func RouteTimeouts(timeouts map[string]time.Duration) ServerOption {
+	return func(s *Server) {
+		if s.routeTimeouts == nil {
+			s.routeTimeouts = make(map[string]time.Duration)
+		}
+		for operation, timeout := range timeouts {
+			s.routeTimeouts[operation] = timeout
+		}
+	}
+}
+
+// MinDeadline with the min remaining deadline of a request,
+// the requests which cannot finish in time are rejected with GatewayTimeout.
+func MinDeadline(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.minDeadline = d
+	}
+}
+
 // Logger with server logger.
 // Deprecated: use global logger instead.
 func Logger(logger log.Logger) ServerOption {
@@ -66,6 +98,14 @@ func Middleware(m ...middleware.Middleware) ServerOption {
 	}
 }
 
+// StreamMiddleware with server stream middleware,
+// which is applied to every message received or sent on a streaming RPC.
+func StreamMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.streamMiddleware = m
+	}
+}
+
 // TLSConfig with TLS config.
 func TLSConfig(c *tls.Config) ServerOption {
 	return func(s *Server) {
@@ -103,21 +143,26 @@ func Options(opts ...grpc.ServerOption) ServerOption {
 
 // Server is a gRPC server wrapper.
 type Server struct {
+	// inflight is accessed atomically and must be 64-bit aligned.
+	inflight int64
 	*grpc.Server
-	baseCtx    context.Context
-	tlsConf    *tls.Config
-	lis        net.Listener
-	err        error
-	network    string
-	address    string
-	endpoint   *url.URL
-	timeout    time.Duration
-	middleware []middleware.Middleware
-	unaryInts  []grpc.UnaryServerInterceptor
-	streamInts []grpc.StreamServerInterceptor
-	grpcOpts   []grpc.ServerOption
-	health     *health.Server
-	metadata   *apimd.Server
+	baseCtx          context.Context
+	tlsConf          *tls.Config
+	lis              net.Listener
+	err              error
+	network          string
+	address          string
+	endpoint         *url.URL
+	timeout          time.Duration
+	minDeadline      time.Duration
+	routeTimeouts    map[string]time.Duration
+	middleware       []middleware.Middleware
+	streamMiddleware []middleware.Middleware
+	unaryInts        []grpc.UnaryServerInterceptor
+	streamInts       []grpc.StreamServerInterceptor
+	grpcOpts         []grpc.ServerOption
+	health           *health.Server
+	metadata         *apimd.Server
 }
 
 // NewServer creates a gRPC server by options.
@@ -186,6 +231,18 @@ func (s *Server) Start(ctx context.Context) error {
 	return s.Serve(s.lis)
 }
 
+// Drain sets the gRPC health status to NOT_SERVING, requests are still handled.
+func (s *Server) Drain(ctx context.Context) error {
+	s.health.Shutdown()
+	log.Infof("[gRPC] server draining, %d requests in flight", s.Inflight())
+	return nil
+}
+
+// Inflight returns the number of gRPC requests and streams in progress.
+func (s *Server) Inflight() int64 {
+	return atomic.LoadInt64(&s.inflight)
+}
+
 // Stop stop the gRPC server.
 func (s *Server) Stop(ctx context.Context) error {
 	s.health.Shutdown()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"reflect"
@@ -17,6 +18,8 @@ import (
 	"github.com/go-kratos/kratos/v2/transport"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	grpcmd "google.golang.org/grpc/metadata"
 )
 
 // server is used to implement helloworld.GreeterServer.
@@ -235,6 +238,58 @@ func TestServer_unaryServerInterceptor(t *testing.T) {
 	}
 }
 
+func TestServer_minDeadline(t *testing.T) {
+	u, err := url.Parse("grpc://hello/world")
+	if err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	srv := &Server{
+		baseCtx:     context.Background(),
+		endpoint:    u,
+		timeout:     time.Second,
+		minDeadline: 100 * time.Millisecond,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &testResp{Data: "hi"}, nil
+	}
+	if _, err = srv.unaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = srv.unaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	if !errors.IsGatewayTimeout(err) {
+		t.Errorf("expect gateway timeout, got %v", err)
+	}
+}
+
+func TestServer_routeTimeout(t *testing.T) {
+	u, err := url.Parse("grpc://hello/world")
+	if err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	srv := &Server{
+		baseCtx:  context.Background(),
+		endpoint: u,
+		timeout:  time.Second,
+	}
+	RouteTimeout("/api.Report/Export", time.Minute)(srv)
+	var remaining time.Duration
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		d, _ := ctx.Deadline()
+		remaining = time.Until(d)
+		return nil, nil
+	}
+	_, _ = srv.unaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api.Report/Export"}, handler)
+	if remaining <= time.Second || remaining > time.Minute {
+		t.Errorf("expect remaining in (%v, %v], got %v", time.Second, time.Minute, remaining)
+	}
+	_, _ = srv.unaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api.Report/Get"}, handler)
+	if remaining > time.Second {
+		t.Errorf("expect remaining in (0, %v], got %v", time.Second, remaining)
+	}
+}
+
 func TestListener(t *testing.T) {
 	lis := &net.TCPListener{}
 	s := &Server{}
@@ -243,3 +298,119 @@ func TestListener(t *testing.T) {
 		t.Errorf("expect %v, got %v", lis, s.lis)
 	}
 }
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv []interface{}
+	sent []interface{}
+}
+
+func (m *mockServerStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockServerStream) SetHeader(grpcmd.MD) error {
+	return nil
+}
+
+func (m *mockServerStream) SendMsg(msg interface{}) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *mockServerStream) RecvMsg(msg interface{}) error {
+	if len(m.recv) == 0 {
+		return io.EOF
+	}
+	msg.(*testResp).Data = m.recv[0].(string)
+	m.recv = m.recv[1:]
+	return nil
+}
+
+func TestServer_streamServerInterceptor(t *testing.T) {
+	u, err := url.Parse("grpc://hello/world")
+	if err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	var events []string
+	srv := &Server{
+		baseCtx:  context.Background(),
+		endpoint: u,
+		middleware: []middleware.Middleware{
+			func(handler middleware.Handler) middleware.Handler {
+				return func(ctx context.Context, req interface{}) (interface{}, error) {
+					if _, ok := transport.FromServerContext(ctx); !ok {
+						t.Errorf("expect server transport in context")
+					}
+					events = append(events, "start")
+					reply, err := handler(ctx, req)
+					events = append(events, "end")
+					return reply, err
+				}
+			},
+		},
+		streamMiddleware: []middleware.Middleware{
+			func(handler middleware.Handler) middleware.Handler {
+				return func(ctx context.Context, req interface{}) (interface{}, error) {
+					events = append(events, "msg:"+req.(*testResp).Data)
+					return handler(ctx, req)
+				}
+			},
+		},
+	}
+	ss := &mockServerStream{ctx: context.Background(), recv: []interface{}{"ping"}}
+	err = srv.streamServerInterceptor()(nil, ss, &grpc.StreamServerInfo{FullMethod: "/test.Stream/Echo"}, func(srv interface{}, stream grpc.ServerStream) error {
+		for {
+			in := &testResp{}
+			if err := stream.RecvMsg(in); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			if err := stream.SendMsg(&testResp{Data: "pong"}); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	expected := []string{"start", "msg:ping", "msg:pong", "end"}
+	if !reflect.DeepEqual(expected, events) {
+		t.Errorf("expect %v, got %v", expected, events)
+	}
+	if len(ss.sent) != 1 {
+		t.Errorf("expect %d, got %d", 1, len(ss.sent))
+	}
+}
+
+func TestStreamMiddleware(t *testing.T) {
+	o := &Server{}
+	v := []middleware.Middleware{
+		func(middleware.Handler) middleware.Handler { return nil },
+	}
+	StreamMiddleware(v...)(o)
+	if !reflect.DeepEqual(v, o.streamMiddleware) {
+		t.Errorf("expect %v, got %v", v, o.streamMiddleware)
+	}
+}
+
+func TestServer_Drain(t *testing.T) {
+	srv := NewServer()
+	srv.health.Resume()
+	if err := srv.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.health.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expect %v, got %v", grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+	}
+	if srv.Inflight() != 0 {
+		t.Errorf("expect %d, got %d", 0, srv.Inflight())
+	}
+}
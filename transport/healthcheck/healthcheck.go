@@ -0,0 +1,282 @@
+package healthcheck
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+// Prober probes a service instance, a nil error means it's healthy.
+type Prober func(ctx context.Context, ins *registry.ServiceInstance) error
+
+// Option is health check option.
+type Option func(*options)
+
+// WithInterval with the interval between probes.
+func WithInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// WithTimeout with the timeout of a probe.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithHealthyThreshold with the number of consecutive successful probes
+// for an unhealthy instance to become healthy.
+func WithHealthyThreshold(n int) Option {
+	return func(o *options) {
+		o.healthy = n
+	}
+}
+
+// WithUnhealthyThreshold with the number of consecutive failed probes
+// for a healthy instance to become unhealthy.
+func WithUnhealthyThreshold(n int) Option {
+	return func(o *options) {
+		o.unhealthy = n
+	}
+}
+
+// WithRelease with the func which releases the resources of an instance kept by the prober,
+// e.g. the connection. It's called when the instance leaves or the checker stops,
+// and never concurrently with the probes.
+func WithRelease(fn func(ins *registry.ServiceInstance)) Option {
+	return func(o *options) {
+		o.release = fn
+	}
+}
+
+type options struct {
+	interval  time.Duration
+	timeout   time.Duration
+	healthy   int
+	unhealthy int
+	release   func(ins *registry.ServiceInstance)
+}
+
+type target struct {
+	ins       *registry.ServiceInstance
+	healthy   bool
+	successes int
+	failures  int
+}
+
+// Checker actively probes the discovered instances, and updates
+// the healthy ones whenever the instances or their health change.
+// A new instance is healthy until it fails the unhealthy threshold.
+type Checker struct {
+	opts   options
+	probe  Prober
+	update func([]*registry.ServiceInstance)
+	// emitMu serializes the updates.
+	emitMu sync.Mutex
+
+	mu      sync.Mutex
+	updated bool
+	targets map[string]*target
+	order   []string
+	// the instances left, which are released before the next probes
+	removed []*registry.ServiceInstance
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a health checker, update is called with the healthy instances,
+// the updates are serialized, so update must not call Update of the checker.
+func New(probe Prober, update func([]*registry.ServiceInstance), opts ...Option) *Checker {
+	o := options{
+		interval:  5 * time.Second,
+		timeout:   time.Second,
+		healthy:   1,
+		unhealthy: 2,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Checker{
+		opts:    o,
+		probe:   probe,
+		update:  update,
+		targets: make(map[string]*target),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go c.run()
+	return c
+}
+
+// Update sets the discovered instances.
+func (c *Checker) Update(ins []*registry.ServiceInstance) {
+	c.mu.Lock()
+	targets := make(map[string]*target, len(ins))
+	order := make([]string, 0, len(ins))
+	for _, in := range ins {
+		k := key(in)
+		if _, ok := targets[k]; ok {
+			continue
+		}
+		t, ok := c.targets[k]
+		if !ok {
+			t = &target{healthy: true}
+		}
+		t.ins = in
+		targets[k] = t
+		order = append(order, k)
+	}
+	if c.opts.release != nil {
+		for k, t := range c.targets {
+			if _, ok := targets[k]; !ok {
+				c.removed = append(c.removed, t.ins)
+			}
+		}
+	}
+	c.targets = targets
+	c.order = order
+	c.updated = true
+	c.mu.Unlock()
+	c.emit()
+}
+
+// Stop stops probing.
+func (c *Checker) Stop() {
+	c.cancel()
+}
+
+func (c *Checker) run() {
+	ticker := time.NewTicker(c.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			c.release(true)
+			return
+		case <-ticker.C:
+			c.release(false)
+			c.check()
+		}
+	}
+}
+
+// release releases the instances left, or all instances if the checker is stopped.
+// It's called by the probing goroutine only, so no probe of the instances is in flight.
+func (c *Checker) release(all bool) {
+	if c.opts.release == nil {
+		return
+	}
+	c.mu.Lock()
+	removed := c.removed
+	c.removed = nil
+	var released []*registry.ServiceInstance
+	for _, ins := range removed {
+		if _, ok := c.targets[key(ins)]; ok {
+			// the instance is back before it's released
+			continue
+		}
+		released = append(released, ins)
+	}
+	if all {
+		for _, t := range c.targets {
+			released = append(released, t.ins)
+		}
+	}
+	c.mu.Unlock()
+	for _, ins := range released {
+		c.opts.release(ins)
+	}
+}
+
+// check probes all the instances, and updates if any health changed.
+func (c *Checker) check() {
+	c.mu.Lock()
+	targets := make([]*target, 0, len(c.targets))
+	ins := make([]*registry.ServiceInstance, 0, len(c.targets))
+	for _, t := range c.targets {
+		targets = append(targets, t)
+		ins = append(ins, t.ins)
+	}
+	c.mu.Unlock()
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i := range ins {
+		wg.Add(1)
+		go func(i int, ins *registry.ServiceInstance) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.ctx, c.opts.timeout)
+			defer cancel()
+			errs[i] = c.probe(ctx, ins)
+		}(i, ins[i])
+	}
+	wg.Wait()
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	changed := false
+	c.mu.Lock()
+	for i, t := range targets {
+		if errs[i] == nil {
+			t.failures = 0
+			t.successes++
+			if !t.healthy && t.successes >= c.opts.healthy {
+				t.healthy = true
+				changed = true
+				log.Infof("[healthcheck] instance %s is healthy", key(t.ins))
+			}
+			continue
+		}
+		t.successes = 0
+		t.failures++
+		if t.healthy && t.failures >= c.opts.unhealthy {
+			t.healthy = false
+			changed = true
+			log.Warnf("[healthcheck] instance %s is unhealthy: %v", key(t.ins), errs[i])
+		}
+	}
+	c.mu.Unlock()
+	if changed {
+		c.emit()
+	}
+}
+
+// emit updates the healthy instances, all instances are updated if none is healthy,
+// as failing open is safer than refusing every request.
+// The updates are serialized, and the callback is called without holding the lock of the targets.
+func (c *Checker) emit() {
+	c.emitMu.Lock()
+	defer c.emitMu.Unlock()
+	c.mu.Lock()
+	if !c.updated {
+		c.mu.Unlock()
+		return
+	}
+	healthy := make([]*registry.ServiceInstance, 0, len(c.order))
+	all := make([]*registry.ServiceInstance, 0, len(c.order))
+	for _, k := range c.order {
+		t := c.targets[k]
+		all = append(all, t.ins)
+		if t.healthy {
+			healthy = append(healthy, t.ins)
+		}
+	}
+	c.mu.Unlock()
+	if len(healthy) == 0 {
+		healthy = all
+	}
+	c.update(healthy)
+}
+
+func key(ins *registry.ServiceInstance) string {
+	return ins.ID + "@" + strings.Join(ins.Endpoints, ",")
+}
@@ -0,0 +1,148 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+type recorder struct {
+	mu  sync.Mutex
+	ins []*registry.ServiceInstance
+}
+
+func (r *recorder) update(ins []*registry.ServiceInstance) {
+	r.mu.Lock()
+	r.ins = ins
+	r.mu.Unlock()
+}
+
+func (r *recorder) ids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.ins))
+	for _, in := range r.ins {
+		ids = append(ids, in.ID)
+	}
+	return ids
+}
+
+func TestChecker(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		down = map[string]bool{"b": true}
+	)
+	probe := func(_ context.Context, ins *registry.ServiceInstance) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if down[ins.ID] {
+			return errors.New("down")
+		}
+		return nil
+	}
+	r := &recorder{}
+	c := New(probe, r.update, WithInterval(10*time.Millisecond), WithUnhealthyThreshold(2), WithHealthyThreshold(2))
+	defer c.Stop()
+
+	c.Update([]*registry.ServiceInstance{{ID: "a"}, {ID: "b"}})
+	if ids := r.ids(); len(ids) != 2 {
+		t.Fatalf("expect %v, got %v", 2, ids)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ids := r.ids(); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expect %v, got %v", []string{"a"}, ids)
+	}
+
+	mu.Lock()
+	down["b"] = false
+	mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	if ids := r.ids(); len(ids) != 2 {
+		t.Fatalf("expect %v, got %v", 2, ids)
+	}
+}
+
+func TestChecker_AllUnhealthy(t *testing.T) {
+	probe := func(context.Context, *registry.ServiceInstance) error {
+		return errors.New("down")
+	}
+	r := &recorder{}
+	c := New(probe, r.update, WithInterval(10*time.Millisecond), WithUnhealthyThreshold(1))
+	defer c.Stop()
+
+	c.Update([]*registry.ServiceInstance{{ID: "a"}, {ID: "b"}})
+	time.Sleep(50 * time.Millisecond)
+	// fail open
+	if ids := r.ids(); len(ids) != 2 {
+		t.Fatalf("expect %v, got %v", 2, ids)
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	probe := func(ctx context.Context, ins *registry.ServiceInstance) error {
+		if ins.ID == "a" {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r := &recorder{}
+	c := New(probe, r.update, WithInterval(10*time.Millisecond), WithTimeout(5*time.Millisecond), WithUnhealthyThreshold(1))
+	defer c.Stop()
+
+	c.Update([]*registry.ServiceInstance{{ID: "a"}, {ID: "b"}})
+	time.Sleep(50 * time.Millisecond)
+	if ids := r.ids(); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("expect %v, got %v", []string{"a"}, ids)
+	}
+}
+
+func TestChecker_Release(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		released []string
+	)
+	probe := func(context.Context, *registry.ServiceInstance) error { return nil }
+	release := func(ins *registry.ServiceInstance) {
+		mu.Lock()
+		released = append(released, ins.ID)
+		mu.Unlock()
+	}
+	r := &recorder{}
+	c := New(probe, r.update, WithInterval(10*time.Millisecond), WithRelease(release))
+	c.Update([]*registry.ServiceInstance{{ID: "a"}, {ID: "b"}})
+	c.Update([]*registry.ServiceInstance{{ID: "a"}})
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	if len(released) != 1 || released[0] != "b" {
+		t.Errorf("expect %v, got %v", []string{"b"}, released)
+	}
+	mu.Unlock()
+	c.Stop()
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	if len(released) != 2 || released[1] != "a" {
+		t.Errorf("expect %v, got %v", []string{"b", "a"}, released)
+	}
+	mu.Unlock()
+}
+
+func TestChecker_ConcurrentUpdate(t *testing.T) {
+	r := &recorder{}
+	probe := func(_ context.Context, ins *registry.ServiceInstance) error {
+		return errors.New(ins.Version)
+	}
+	c := New(probe, r.update, WithInterval(time.Millisecond), WithUnhealthyThreshold(1))
+	defer c.Stop()
+	for i := 0; i < 100; i++ {
+		c.Update([]*registry.ServiceInstance{{ID: "a", Version: "v1"}})
+		time.Sleep(100 * time.Microsecond)
+	}
+	if ids := r.ids(); len(ids) != 1 {
+		t.Errorf("expect %v, got %v", []string{"a"}, ids)
+	}
+}
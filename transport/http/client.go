@@ -11,13 +11,17 @@ import (
 
 	"github.com/go-kratos/kratos/v2/encoding"
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/deadline"
+	"github.com/go-kratos/kratos/v2/internal/endpoint"
 	"github.com/go-kratos/kratos/v2/internal/host"
 	"github.com/go-kratos/kratos/v2/internal/httputil"
+	ireply "github.com/go-kratos/kratos/v2/internal/reply"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/go-kratos/kratos/v2/selector/wrr"
 	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/healthcheck"
 )
 
 // DecodeErrorFunc is decode error func.
@@ -47,6 +51,10 @@ type clientOptions struct {
 	discovery    registry.Discovery
 	middleware   []middleware.Middleware
 	block        bool
+	healthPath   string
+	healthOpts   []healthcheck.Option
+	subsetKey    string
+	subsetSize   int
 }
 
 // WithTransport with client transport.
@@ -126,6 +134,25 @@ func WithBlock() ClientOption {
 	}
 }
 
+// WithHealthCheck with active health checking of the discovered nodes,
+// a node is healthy if GET path responds with 2xx.
+func WithHealthCheck(path string, opts ...healthcheck.Option) ClientOption {
+	return func(o *clientOptions) {
+		o.healthPath = path
+		o.healthOpts = opts
+	}
+}
+
+// WithSubset with deterministic subsetting of the discovered nodes,
+// the client only balances over a stable subset of the nodes with the size,
+// which is keyed by the client key, usually the app ID.
+func WithSubset(key string, size int) ClientOption {
+	return func(o *clientOptions) {
+		o.subsetKey = key
+		o.subsetSize = size
+	}
+}
+
 // WithTLSConfig with tls config.
 func WithTLSConfig(c *tls.Config) ClientOption {
 	return func(o *clientOptions) {
@@ -166,10 +193,18 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the timeout is applied by the request context, so that the client is shared by the event streams.
+	cc := &http.Client{
+		Transport: options.transport,
+	}
 	var r *resolver
 	if options.discovery != nil {
 		if target.Scheme == "discovery" {
-			if r, err = newResolver(ctx, options.discovery, target, options.selector, options.block, insecure); err != nil {
+			var probe healthcheck.Prober
+			if options.healthPath != "" {
+				probe = healthProber(cc, options.healthPath, insecure)
+			}
+			if r, err = newResolver(ctx, options.discovery, target, options.selector, options.block, insecure, options.subsetKey, options.subsetSize, probe, options.healthOpts...); err != nil {
 				return nil, fmt.Errorf("[http client] new resolver failed!err: %v", options.endpoint)
 			}
 		} else if _, _, err := host.ExtractHostPort(options.endpoint); err != nil {
@@ -181,10 +216,7 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 		target:   target,
 		insecure: insecure,
 		r:        r,
-		cc: &http.Client{
-			Timeout:   options.timeout,
-			Transport: options.transport,
-		},
+		cc:       cc,
 	}, nil
 }
 
@@ -231,7 +263,17 @@ func (client *Client) Invoke(ctx context.Context, method, path string, args inte
 
 func (client *Client) invoke(ctx context.Context, req *http.Request, args interface{}, reply interface{}, c callInfo, opts ...CallOption) error {
 	h := func(ctx context.Context, in interface{}) (interface{}, error) {
-		res, err := client.do(req.WithContext(ctx))
+		// the request is cloned, as the handler may be invoked concurrently, e.g. by hedging.
+		r := req.Clone(ctx)
+		if req.GetBody != nil {
+			// the body is rewound, as the handler may be invoked more than once, e.g. by retries.
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		res, err := client.do(r)
 		if res != nil {
 			cs := csAttempt{res: res}
 			for _, o := range opts {
@@ -242,17 +284,25 @@ func (client *Client) invoke(ctx context.Context, req *http.Request, args interf
 			return nil, err
 		}
 		defer res.Body.Close()
-		if err := client.opts.decoder(ctx, res, reply); err != nil {
+		out := reply
+		if ireply.Isolated(ctx) {
+			out = ireply.New(reply)
+		}
+		if err := client.opts.decoder(ctx, res, out); err != nil {
 			return nil, err
 		}
-		return reply, nil
+		return out, nil
 	}
 	var p selector.Peer
 	ctx = selector.NewPeerContext(ctx, &p)
 	if len(client.opts.middleware) > 0 {
 		h = middleware.Chain(client.opts.middleware...)(h)
 	}
-	_, err := h(ctx, args)
+	out, err := h(ctx, args)
+	if err == nil && out != nil {
+		// the reply returned by the middleware may not be the caller's one.
+		err = ireply.Copy(reply, out)
+	}
 	return err
 }
 
@@ -265,11 +315,17 @@ func (client *Client) Do(req *http.Request, opts ...CallOption) (*http.Response,
 			return nil, err
 		}
 	}
-
-	return client.do(req)
+	// the request is cloned, as the headers and the url are set by the round trip.
+	return client.do(req.Clone(req.Context()))
 }
 
 func (client *Client) do(req *http.Request) (*http.Response, error) {
+	return client.roundTrip(client.opts.timeout, req)
+}
+
+// roundTrip selects the node, and sends the request by the http client with the timeout,
+// which covers the reading of the response body, it's not applied if zero.
+func (client *Client) roundTrip(timeout time.Duration, req *http.Request) (*http.Response, error) {
 	var done func(context.Context, selector.DoneInfo)
 	if client.r != nil {
 		var (
@@ -287,6 +343,16 @@ func (client *Client) do(req *http.Request) (*http.Response, error) {
 		req.URL.Host = node.Address()
 		req.Host = node.Address()
 	}
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), timeout)
+		req = req.WithContext(ctx)
+	}
+	// the remaining deadline is propagated to the server.
+	if d, ok := req.Context().Deadline(); ok {
+		req.Header.Set(deadline.Header, deadline.Encode(time.Until(d)))
+	}
 	resp, err := client.cc.Do(req)
 	if err == nil {
 		err = client.opts.errorDecoder(req.Context(), resp)
@@ -295,11 +361,25 @@ func (client *Client) do(req *http.Request) (*http.Response, error) {
 		done(req.Context(), selector.DoneInfo{Err: err})
 	}
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
 	return resp, nil
 }
 
+// cancelBody cancels the context of the request once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // Close tears down the Transport and all underlying connections.
 func (client *Client) Close() error {
 	if client.r != nil {
@@ -308,6 +388,38 @@ func (client *Client) Close() error {
 	return nil
 }
 
+// healthProber returns a prober which requests the health path of the instance.
+func healthProber(cc *http.Client, path string, insecure bool) healthcheck.Prober {
+	return func(ctx context.Context, ins *registry.ServiceInstance) error {
+		addr, err := endpoint.ParseEndpoint(ins.Endpoints, endpoint.Scheme("http", !insecure))
+		if err != nil {
+			return err
+		}
+		if addr == "" {
+			// the instance is skipped by the resolver
+			return nil
+		}
+		scheme := "http"
+		if !insecure {
+			scheme = "https"
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s%s", scheme, addr, path), nil)
+		if err != nil {
+			return err
+		}
+		res, err := cc.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(io.Discard, res.Body)
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("health status: %d", res.StatusCode)
+		}
+		return nil
+	}
+}
+
 // DefaultRequestEncoder is an HTTP request encoder.
 func DefaultRequestEncoder(ctx context.Context, contentType string, in interface{}) ([]byte, error) {
 	name := httputil.ContentSubtype(contentType)
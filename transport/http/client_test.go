@@ -9,12 +9,14 @@ import (
 	"fmt"
 	"io"
 	nethttp "net/http"
+	"net/http/httptest"
 	"reflect"
 	"strconv"
 	"testing"
 	"time"
 
 	kratosErrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/deadline"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
 )
@@ -165,6 +167,46 @@ func TestWithDiscovery(t *testing.T) {
 	}
 }
 
+func TestWithHealthCheck(t *testing.T) {
+	o := WithHealthCheck("/healthz")
+	co := &clientOptions{}
+	o(co)
+	if co.healthPath != "/healthz" {
+		t.Errorf("expected health path to be %v, got %v", "/healthz", co.healthPath)
+	}
+}
+
+func TestWithSubset(t *testing.T) {
+	o := WithSubset("client", 10)
+	co := &clientOptions{}
+	o(co)
+	if co.subsetKey != "client" || co.subsetSize != 10 {
+		t.Errorf("expected subset to be %v %v, got %v %v", "client", 10, co.subsetKey, co.subsetSize)
+	}
+}
+
+func TestHealthProber(t *testing.T) {
+	status := nethttp.StatusOK
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(nethttp.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	probe := healthProber(srv.Client(), "/healthz", true)
+	ins := &registry.ServiceInstance{Endpoints: []string{srv.URL}}
+	if err := probe(context.Background(), ins); err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	status = nethttp.StatusServiceUnavailable
+	if err := probe(context.Background(), ins); err == nil {
+		t.Errorf("expect error, got %v", err)
+	}
+}
+
 func TestDefaultRequestEncoder(t *testing.T) {
 	req1 := &nethttp.Request{
 		Header: make(nethttp.Header),
@@ -306,3 +348,39 @@ func TestNewClient(t *testing.T) {
 		t.Error("err should not be equal to nil")
 	}
 }
+
+func TestDeadlinePropagation(t *testing.T) {
+	var timeout string
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		timeout = r.Header.Get(deadline.Header)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(context.Background(), WithEndpoint(srv.Listener.Addr().String()), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(ctx context.Context, max time.Duration) {
+		req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = client.Do(req); err != nil {
+			t.Fatal(err)
+		}
+		if v := req.Header.Get(deadline.Header); v != "" {
+			t.Errorf("expect the request of the caller unchanged, got %v", v)
+		}
+		d, err := deadline.Decode(timeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d <= 0 || d > max {
+			t.Errorf("expect timeout in (0, %v], got %v", max, d)
+		}
+	}
+	check(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	check(ctx, 100*time.Millisecond)
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/middleware"
@@ -99,14 +100,46 @@ func (c *wrapper) Returns(v interface{}, err error) error {
 	if err != nil {
 		return err
 	}
+	if c.notModified(http.StatusOK) {
+		return nil
+	}
 	return c.router.srv.enc(&c.w, c.req, v)
 }
 
 func (c *wrapper) Result(code int, v interface{}) error {
+	if c.notModified(code) {
+		return nil
+	}
 	c.w.WriteHeader(code)
 	return c.router.srv.enc(&c.w, c.req, v)
 }
 
+// notModified replies 304 Not Modified without the body, if the ETag reply header
+// matches the If-None-Match of the GET or HEAD request, e.g. the ETag set by the cache middleware.
+func (c *wrapper) notModified(code int) bool {
+	if code != http.StatusOK || c.req == nil || (c.req.Method != http.MethodGet && c.req.Method != http.MethodHead) {
+		return false
+	}
+	etag := c.res.Header().Get("ETag")
+	if etag == "" || !matchETag(c.req.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	c.res.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// matchETag reports whether the If-None-Match header matches the etag by the weak comparison.
+func matchETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *wrapper) JSON(code int, v interface{}) error {
 	c.res.Header().Set("Content-Type", "application/json")
 	c.res.WriteHeader(code)
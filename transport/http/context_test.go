@@ -162,6 +162,43 @@ func TestContextResponseReturn(t *testing.T) {
 	}
 }
 
+func TestContextResultNotModified(t *testing.T) {
+	res := httptest.NewRecorder()
+	res.Header().Set("ETag", `"b"`)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"a", W/"b"`)
+	w := &wrapper{router: &Router{srv: &Server{enc: DefaultResponseEncoder}}}
+	w.Reset(res, req)
+	if err := w.Result(200, "success"); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	if res.Code != http.StatusNotModified || res.Body.Len() != 0 {
+		t.Errorf("expected %v without body, got %v %s", http.StatusNotModified, res.Code, res.Body.String())
+	}
+
+	res = httptest.NewRecorder()
+	res.Header().Set("ETag", `"c"`)
+	w.Reset(res, req)
+	if err := w.Result(200, map[string]string{"a": "b"}); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	if res.Code != http.StatusOK || res.Body.Len() == 0 {
+		t.Errorf("expected %v with body, got %v", http.StatusOK, res.Code)
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	if !matchETag(`"a", W/"b"`, `"b"`) {
+		t.Errorf("expect match")
+	}
+	if matchETag(`"a"`, `"b"`) {
+		t.Errorf("expect not match")
+	}
+	if !matchETag(`*`, `"b"`) {
+		t.Errorf("expect match")
+	}
+}
+
 func TestContextCtx(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/subset"
+	"github.com/go-kratos/kratos/v2/transport/healthcheck"
 )
 
 // Target is resolver target
@@ -46,9 +49,14 @@ type resolver struct {
 	watcher registry.Watcher
 
 	insecure bool
+	checker  *healthcheck.Checker
+	applied  int32
+
+	subsetKey  string
+	subsetSize int
 }
 
-func newResolver(ctx context.Context, discovery registry.Discovery, target *Target, rebalancer selector.Rebalancer, block, insecure bool) (*resolver, error) {
+func newResolver(ctx context.Context, discovery registry.Discovery, target *Target, rebalancer selector.Rebalancer, block, insecure bool, subsetKey string, subsetSize int, probe healthcheck.Prober, checkOpts ...healthcheck.Option) (*resolver, error) {
 	watcher, err := discovery.Watch(ctx, target.Endpoint)
 	if err != nil {
 		return nil, err
@@ -58,6 +66,11 @@ func newResolver(ctx context.Context, discovery registry.Discovery, target *Targ
 		watcher:    watcher,
 		rebalancer: rebalancer,
 		insecure:   insecure,
+		subsetKey:  subsetKey,
+		subsetSize: subsetSize,
+	}
+	if probe != nil {
+		r.checker = healthcheck.New(probe, func(services []*registry.ServiceInstance) { r.update(services) }, checkOpts...)
 	}
 	if block {
 		done := make(chan error, 1)
@@ -68,7 +81,7 @@ func newResolver(ctx context.Context, discovery registry.Discovery, target *Targ
 					done <- err
 					return
 				}
-				if r.update(services) {
+				if r.next(services) {
 					done <- nil
 					return
 				}
@@ -85,6 +98,7 @@ func newResolver(ctx context.Context, discovery registry.Discovery, target *Targ
 			}
 		case <-ctx.Done():
 			log.Errorf("http client watch service %v reaching context deadline!", target)
+			r.stopChecker()
 			stopErr := watcher.Stop()
 			if stopErr != nil {
 				log.Errorf("failed to http client watch stop: %v, error: %+v", target, stopErr)
@@ -103,13 +117,24 @@ func newResolver(ctx context.Context, discovery registry.Discovery, target *Targ
 				time.Sleep(time.Second)
 				continue
 			}
-			r.update(services)
+			r.next(services)
 		}
 	}()
 	return r, nil
 }
 
+// next updates the watched services, through the health checker if any,
+// the subset is taken from the healthy services so it's refilled when any is unhealthy.
+func (r *resolver) next(services []*registry.ServiceInstance) bool {
+	if r.checker == nil {
+		return r.update(services)
+	}
+	r.checker.Update(services)
+	return atomic.LoadInt32(&r.applied) == 1
+}
+
 func (r *resolver) update(services []*registry.ServiceInstance) bool {
+	services = subset.Subset(r.subsetKey, r.subsetSize, services)
 	nodes := make([]selector.Node, 0)
 	for _, ins := range services {
 		ept, err := endpoint.ParseEndpoint(ins.Endpoints, endpoint.Scheme("http", !r.insecure))
@@ -127,9 +152,17 @@ func (r *resolver) update(services []*registry.ServiceInstance) bool {
 		return false
 	}
 	r.rebalancer.Apply(nodes)
+	atomic.StoreInt32(&r.applied, 1)
 	return true
 }
 
+func (r *resolver) stopChecker() {
+	if r.checker != nil {
+		r.checker.Stop()
+	}
+}
+
 func (r *resolver) Close() error {
+	r.stopChecker()
 	return r.watcher.Stop()
 }
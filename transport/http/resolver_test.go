@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/subset"
+	"github.com/go-kratos/kratos/v2/transport/healthcheck"
 )
 
 func TestParseTarget(t *testing.T) {
@@ -104,12 +107,54 @@ func TestResolver(t *testing.T) {
 		Authority: "",
 		Endpoint:  "discovery://helloworld",
 	}
-	_, err := newResolver(context.Background(), &mockDiscoveries{true}, ta, &mockRebalancer{}, false, false)
+	_, err := newResolver(context.Background(), &mockDiscoveries{true}, ta, &mockRebalancer{}, false, false, "", 0, nil)
 	if err != nil {
 		t.Errorf("expect %v, got %v", nil, err)
 	}
-	_, err = newResolver(context.Background(), &mockDiscoveries{false}, ta, &mockRebalancer{}, true, true)
+	_, err = newResolver(context.Background(), &mockDiscoveries{false}, ta, &mockRebalancer{}, true, true, "", 0, nil)
 	if err != nil {
 		t.Errorf("expect %v, got %v", nil, err)
 	}
 }
+
+type recordRebalancer struct {
+	mu    sync.Mutex
+	nodes []selector.Node
+}
+
+func (r *recordRebalancer) Apply(nodes []selector.Node) {
+	r.mu.Lock()
+	r.nodes = nodes
+	r.mu.Unlock()
+}
+
+func TestResolverSubsetHealthy(t *testing.T) {
+	services := make([]*registry.ServiceInstance, 0, 3)
+	for i := 0; i < 3; i++ {
+		id := strconv.Itoa(i)
+		services = append(services, &registry.ServiceInstance{ID: id, Endpoints: []string{"http://127.0.0.1:80" + id}})
+	}
+	// the instance in the subset of all instances is unhealthy
+	down := subset.Subset("client", 1, services)[0].ID
+	probe := func(_ context.Context, ins *registry.ServiceInstance) error {
+		if ins.ID == down {
+			return errors.New("down")
+		}
+		return nil
+	}
+	rb := &recordRebalancer{}
+	r := &resolver{rebalancer: rb, target: &Target{}, insecure: true, subsetKey: "client", subsetSize: 1}
+	r.checker = healthcheck.New(probe, func(services []*registry.ServiceInstance) { r.update(services) },
+		healthcheck.WithInterval(10*time.Millisecond), healthcheck.WithUnhealthyThreshold(1))
+	defer r.checker.Stop()
+	r.next(services)
+	time.Sleep(100 * time.Millisecond)
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if len(rb.nodes) != 1 {
+		t.Fatalf("expect %v, got %v", 1, len(rb.nodes))
+	}
+	if addr := rb.nodes[0].Address(); addr == "127.0.0.1:80"+down {
+		t.Errorf("expect the subset to be refilled with a healthy instance, got %v", addr)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"path"
 	"sync"
+	"time"
 )
 
 // HandlerFunc defines a function to serve HTTP requests.
@@ -53,6 +54,21 @@ func (r *Router) Handle(method, relativePath string, h HandlerFunc, filters ...F
 	r.srv.router.Handle(path.Join(r.prefix, relativePath), next).Methods(method)
 }
 
+// Operation binds the operation to the route of the method and path, so that the route
+// timeouts can be keyed by the operation. The timeout is the default timeout of the operation,
+// e.g. declared by the proto method option, which is overridden by the route timeouts
+// of the server, it's ignored if zero.
+// The operation is only used to look up the timeouts, the operation of the transport
+// is still the path template in the filters, until the handler sets it by SetOperation.
+func (r *Router) Operation(method, relativePath, operation string, timeout time.Duration) {
+	r.srv.operationMu.Lock()
+	defer r.srv.operationMu.Unlock()
+	r.srv.operations[method+" "+path.Join(r.prefix, relativePath)] = operation
+	if timeout > 0 {
+		r.srv.declaredTimeouts[operation] = timeout
+	}
+}
+
 // GET registers a new GET route for a path with matching handler in the router.
 func (r *Router) GET(path string, h HandlerFunc, m ...FilterFunc) {
 	r.Handle(http.MethodGet, path, h, m...)
@@ -7,8 +7,11 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/internal/deadline"
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
 
 	"github.com/go-kratos/kratos/v2/internal/host"
@@ -22,6 +25,7 @@ import (
 var (
 	_ transport.Server     = (*Server)(nil)
 	_ transport.Endpointer = (*Server)(nil)
+	_ transport.Drainer    = (*Server)(nil)
 )
 
 // ServerOption is an HTTP server option.
@@ -48,6 +52,37 @@ func Timeout(timeout time.Duration) ServerOption {
 	}
 }
 
+// RouteTimeout with the timeout of the route, which is an operation or a path template,
+// it overrides the server timeout.
+func RouteTimeout(route string, timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		if s.routeTimeouts == nil {
+			s.routeTimeouts = make(map[string]time.Duration)
+		}
+		s.routeTimeouts[route] = timeout
+	}
+}
+
+// RouteTimeouts with the timeouts of the routes, e.g. transport.RouteTimeouts loaded from config.
+func RouteTimeouts(timeouts map[string]time.Duration) ServerOption {
+	return func(s *Server) {
+		if s.routeTimeouts == nil {
+			s.routeTimeouts = make(map[string]time.Duration)
+		}
+		for route, timeout := range timeouts {
+			s.routeTimeouts[route] = timeout
+		}
+	}
+}
+
+// MinDeadline with the min remaining deadline of a request,
+// the requests which cannot finish in time are rejected with GatewayTimeout.
+func MinDeadline(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.minDeadline = d
+	}
+}
+
 // Logger with server logger.
 // Deprecated: use global logger instead.
 func Logger(logger log.Logger) ServerOption {
@@ -112,8 +147,18 @@ func Listener(lis net.Listener) ServerOption {
 	}
 }
 
+// HealthPath with the health check path, which responds
+// 200 while serving and 503 once the server is draining.
+func HealthPath(path string) ServerOption {
+	return func(s *Server) {
+		s.healthPath = path
+	}
+}
+
 // Server is an HTTP server wrapper.
 type Server struct {
+	// inflight is accessed atomically and must be 64-bit aligned.
+	inflight int64
 	*http.Server
 	lis         net.Listener
 	tlsConf     *tls.Config
@@ -122,6 +167,14 @@ type Server struct {
 	network     string
 	address     string
 	timeout     time.Duration
+	minDeadline time.Duration
+	// the timeouts keyed by the operation or the path template.
+	routeTimeouts map[string]time.Duration
+	// the timeouts declared with the operations, e.g. by the proto method option.
+	declaredTimeouts map[string]time.Duration
+	// the operations keyed by the method and the path template.
+	operations  map[string]string
+	operationMu sync.RWMutex
 	filters     []FilterFunc
 	ms          []middleware.Middleware
 	dec         DecodeRequestFunc
@@ -129,6 +182,8 @@ type Server struct {
 	ene         EncodeErrorFunc
 	strictSlash bool
 	router      *mux.Router
+	healthPath  string
+	draining    int32
 }
 
 // NewServer creates an HTTP server by options.
@@ -141,6 +196,10 @@ func NewServer(opts ...ServerOption) *Server {
 		enc:         DefaultResponseEncoder,
 		ene:         DefaultErrorEncoder,
 		strictSlash: true,
+
+		routeTimeouts:    make(map[string]time.Duration),
+		declaredTimeouts: make(map[string]time.Duration),
+		operations:       make(map[string]string),
 	}
 	for _, o := range opts {
 		o(srv)
@@ -149,8 +208,11 @@ func NewServer(opts ...ServerOption) *Server {
 	srv.router.NotFoundHandler = http.DefaultServeMux
 	srv.router.MethodNotAllowedHandler = http.DefaultServeMux
 	srv.router.Use(srv.filter())
+	if srv.healthPath != "" {
+		srv.router.HandleFunc(srv.healthPath, srv.health)
+	}
 	srv.Server = &http.Server{
-		Handler:   FilterChain(srv.filters...)(srv.router),
+		Handler:   srv.count(FilterChain(srv.filters...)(srv.router)),
 		TLSConfig: srv.tlsConf,
 	}
 	srv.err = srv.listenAndEndpoint()
@@ -194,18 +256,29 @@ func (s *Server) filter() mux.MiddlewareFunc {
 				ctx    context.Context
 				cancel context.CancelFunc
 			)
-			if s.timeout > 0 {
-				ctx, cancel = context.WithTimeout(req.Context(), s.timeout)
-			} else {
-				ctx, cancel = context.WithCancel(req.Context())
-			}
-			defer cancel()
-
 			pathTemplate := req.URL.Path
 			if route := mux.CurrentRoute(req); route != nil {
 				// /path/123 -> /path/{id}
 				pathTemplate, _ = route.GetPathTemplate()
 			}
+			// the timeout is the min of the route timeout and the client deadline.
+			timeout := s.routeTimeout(req.Method, pathTemplate)
+			var exceeded bool
+			if v := req.Header.Get(deadline.Header); v != "" {
+				if d, err := deadline.Decode(v); err == nil {
+					// the client deadline has been exceeded before the request arrives.
+					exceeded = d <= 0
+					if timeout <= 0 || d < timeout {
+						timeout = d
+					}
+				}
+			}
+			if timeout > 0 {
+				ctx, cancel = context.WithTimeout(req.Context(), timeout)
+			} else {
+				ctx, cancel = context.WithCancel(req.Context())
+			}
+			defer cancel()
 
 			tr := &Transport{
 				endpoint:     s.endpoint.String(),
@@ -217,11 +290,57 @@ func (s *Server) filter() mux.MiddlewareFunc {
 			}
 
 			tr.request = req.WithContext(transport.NewServerContext(ctx, tr))
+			if exceeded {
+				s.ene(w, tr.request, deadline.ErrExceeded)
+				return
+			}
+			if err := deadline.Check(ctx, s.minDeadline); err != nil {
+				s.ene(w, tr.request, err)
+				return
+			}
 			next.ServeHTTP(w, tr.request)
 		})
 	}
 }
 
+// routeTimeout returns the timeout of the operation bound to the route, or the path template,
+// the routes configured on the server take precedence over the declared ones.
+func (s *Server) routeTimeout(method, pathTemplate string) time.Duration {
+	s.operationMu.RLock()
+	operation, ok := s.operations[method+" "+pathTemplate]
+	declared, declaredOK := s.declaredTimeouts[operation]
+	s.operationMu.RUnlock()
+	if ok {
+		if timeout, ok := s.routeTimeouts[operation]; ok {
+			return timeout
+		}
+	}
+	if timeout, ok := s.routeTimeouts[pathTemplate]; ok {
+		return timeout
+	}
+	if ok && declaredOK {
+		return declared
+	}
+	return s.timeout
+}
+
+// count tracks the number of requests in flight.
+func (s *Server) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&s.inflight, 1)
+		defer atomic.AddInt64(&s.inflight, -1)
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (s *Server) health(w http.ResponseWriter, req *http.Request) {
+	if atomic.LoadInt32(&s.draining) == 1 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 // Endpoint return a real address to registry endpoint.
 // examples:
 //   https://127.0.0.1:8000
@@ -254,6 +373,18 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// Drain marks the HTTP server as not serving, requests are still handled.
+func (s *Server) Drain(ctx context.Context) error {
+	atomic.StoreInt32(&s.draining, 1)
+	log.Infof("[HTTP] server draining, %d requests in flight", s.Inflight())
+	return nil
+}
+
+// Inflight returns the number of HTTP requests in progress.
+func (s *Server) Inflight() int64 {
+	return atomic.LoadInt64(&s.inflight)
+}
+
 // Stop stop the HTTP server.
 func (s *Server) Stop(ctx context.Context) error {
 	log.Info("[HTTP] server stopping")
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
@@ -15,7 +16,9 @@ import (
 
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
 
+	"github.com/go-kratos/kratos/v2/internal/deadline"
 	"github.com/go-kratos/kratos/v2/internal/host"
 )
 
@@ -256,6 +259,107 @@ func TestTimeout(t *testing.T) {
 	}
 }
 
+func TestDeadline(t *testing.T) {
+	srv := NewServer(Timeout(time.Second), MinDeadline(50*time.Millisecond))
+	var remaining time.Duration
+	srv.HandleFunc("/deadline", func(w http.ResponseWriter, r *http.Request) {
+		d, _ := r.Context().Deadline()
+		remaining = time.Until(d)
+	})
+	tests := []struct {
+		timeout string
+		code    int
+		max     time.Duration
+	}{
+		{"", http.StatusOK, time.Second},
+		{"200m", http.StatusOK, 200 * time.Millisecond},
+		{"5S", http.StatusOK, time.Second},
+		{"10m", http.StatusGatewayTimeout, 0},
+		{"0n", http.StatusGatewayTimeout, 0},
+	}
+	for _, test := range tests {
+		remaining = 0
+		req := httptest.NewRequest(http.MethodGet, "/deadline", nil)
+		if test.timeout != "" {
+			req.Header.Set(deadline.Header, test.timeout)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expected %d got %d", test.code, w.Code)
+		}
+		if remaining > test.max || (test.max > 0 && remaining <= 0) {
+			t.Errorf("expected remaining in (0, %v] got %v", test.max, remaining)
+		}
+	}
+
+	// an exceeded deadline is rejected without the min deadline.
+	srv = NewServer(Timeout(0))
+	var called bool
+	srv.HandleFunc("/deadline", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	req := httptest.NewRequest(http.MethodGet, "/deadline", nil)
+	req.Header.Set(deadline.Header, "0n")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusGatewayTimeout || called {
+		t.Errorf("expected %d got %d, called %v", http.StatusGatewayTimeout, w.Code, called)
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	srv := NewServer(
+		Timeout(time.Second),
+		RouteTimeout("/api.Report/Export", time.Minute),
+		RouteTimeouts(map[string]time.Duration{"/v1/files/{name}": 10 * time.Second}),
+	)
+	var (
+		remaining time.Duration
+		operation string
+	)
+	h := func(ctx Context) error {
+		d, _ := ctx.Deadline()
+		remaining = time.Until(d)
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			operation = tr.Operation()
+		}
+		return nil
+	}
+	r := srv.Route("/")
+	r.GET("/v1/reports", h)
+	r.Operation(http.MethodGet, "/v1/reports", "/api.Report/Export", 0)
+	r.GET("/v1/files/{name}", h)
+	r.GET("/v1/declared", h)
+	r.Operation(http.MethodGet, "/v1/declared", "/api.Report/Declared", 30*time.Second)
+	r.GET("/v1/other", h)
+	tests := []struct {
+		path      string
+		operation string
+		min       time.Duration
+		max       time.Duration
+	}{
+		// the operation of the transport is still the path template without SetOperation.
+		{"/v1/reports", "/v1/reports", 50 * time.Second, time.Minute},
+		{"/v1/files/a.txt", "/v1/files/{name}", 5 * time.Second, 10 * time.Second},
+		{"/v1/declared", "/v1/declared", 20 * time.Second, 30 * time.Second},
+		{"/v1/other", "/v1/other", 0, time.Second},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+		}
+		if operation != test.operation {
+			t.Errorf("expected %s got %s", test.operation, operation)
+		}
+		if remaining <= test.min || remaining > test.max {
+			t.Errorf("expected remaining in (%v, %v] got %v", test.min, test.max, remaining)
+		}
+	}
+}
+
 func TestLogger(t *testing.T) {
 	// todo
 }
@@ -315,3 +419,42 @@ func TestListener(t *testing.T) {
 		t.Errorf("expected %v got %v", lis, s.lis)
 	}
 }
+
+func TestHealthPath(t *testing.T) {
+	srv := NewServer(HealthPath("/healthz"))
+	if srv.healthPath != "/healthz" {
+		t.Errorf("expected %s got %s", "/healthz", srv.healthPath)
+	}
+	check := func(code int) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if w.Code != code {
+			t.Errorf("expected %d got %d", code, w.Code)
+		}
+	}
+	check(http.StatusOK)
+	if err := srv.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	check(http.StatusServiceUnavailable)
+	if srv.Inflight() != 0 {
+		t.Errorf("expected %d got %d", 0, srv.Inflight())
+	}
+}
+
+func TestRouteOperationConcurrent(t *testing.T) {
+	srv := NewServer()
+	r := srv.Route("/")
+	r.GET("/v1/reports", func(ctx Context) error { return nil })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			r.Operation(http.MethodGet, fmt.Sprintf("/v1/reports/%d", i), "/api.Report/Export", time.Minute)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/reports", nil))
+	}
+	<-done
+}
@@ -0,0 +1,374 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/internal/httputil"
+	istream "github.com/go-kratos/kratos/v2/internal/stream"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// ErrFlusherNotSupported is returned if the response writer cannot be flushed.
+var ErrFlusherNotSupported = errors.New("http: the response writer does not support flushing")
+
+// Event is a server-sent event.
+type Event struct {
+	// ID is the event id, which is sent back by the client in the Last-Event-ID header on reconnection.
+	ID string
+	// Event is the event type, the default is "message".
+	Event string
+	// Retry is the reconnection time of the client.
+	Retry time.Duration
+	// Data is the event data, a string or []byte is sent as is, others are encoded by the codec.
+	// It's the raw []byte of the data when received by the EventReader.
+	Data interface{}
+}
+
+// EventWriter writes the server-sent events to the response, it's started in a handler by SSE,
+// and must be closed before the handler returns.
+// The handler should return once the client is gone, which is reported by Done.
+// The server timeout is applied to the event streams as well,
+// which can be disabled by RouteTimeout(path, 0).
+type EventWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ctx     context.Context
+	codec   encoding.Codec
+	closed  bool
+	stop    chan struct{}
+	// done is closed when the keepalive exits, it's nil if the keepalive is not started.
+	done chan struct{}
+}
+
+// NewEventWriter starts the event stream of the request, the data is encoded
+// by the codec of the request Content-Type, or json by default.
+func NewEventWriter(w http.ResponseWriter, r *http.Request) (*EventWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrFlusherNotSupported
+	}
+	codec, _ := CodecForRequest(r, "Content-Type")
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// disables the response buffering of nginx.
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &EventWriter{
+		w:       w,
+		flusher: flusher,
+		ctx:     r.Context(),
+		codec:   codec,
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// SSE starts the event stream in the handler, it's short for NewEventWriter(ctx.Response(), ctx.Request()).
+//
+//	r.GET("/events", func(ctx http.Context) error {
+//		w, err := http.SSE(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		defer w.Close()
+//		...
+//	})
+func SSE(ctx Context) (*EventWriter, error) {
+	return NewEventWriter(ctx.Response(), ctx.Request())
+}
+
+// Done returns a channel which is closed when the client disconnects.
+func (w *EventWriter) Done() <-chan struct{} {
+	return w.ctx.Done()
+}
+
+// Send writes the event and flushes it to the client.
+func (w *EventWriter) Send(e *Event) error {
+	var buf bytes.Buffer
+	if e.ID != "" {
+		buf.WriteString("id: " + oneLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + oneLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n")
+	}
+	var data []byte
+	switch v := e.Data.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = w.codec.Marshal(v); err != nil {
+			return err
+		}
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+	return w.write(buf.Bytes())
+}
+
+// Comment writes a comment, which is ignored by the client.
+func (w *EventWriter) Comment(text string) error {
+	var buf bytes.Buffer
+	for _, line := range strings.Split(text, "\n") {
+		buf.WriteString(": " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	buf.WriteString("\n")
+	return w.write(buf.Bytes())
+}
+
+// KeepAlive writes an empty comment every interval to keep the connection from
+// being closed by the proxies, until the client disconnects or the writer is closed.
+func (w *EventWriter) KeepAlive(interval time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.done != nil {
+		return
+	}
+	w.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := w.write([]byte(":\n\n")); err != nil {
+					return
+				}
+			case <-w.ctx.Done():
+				return
+			case <-w.stop:
+				return
+			}
+		}
+	}(w.done)
+}
+
+// Close stops the keepalive and waits for it to exit, nothing is written after it returns.
+// The stream is ended once the handler returns.
+func (w *EventWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stop)
+	done := w.done
+	w.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	return nil
+}
+
+func (w *EventWriter) write(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+	w.flusher.Flush()
+	return nil
+}
+
+// oneLine drops the line breaks, which are not allowed in the id and event fields.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// EventReader reads the server-sent events from the response.
+type EventReader struct {
+	body   io.ReadCloser
+	r      *bufio.Reader
+	codec  encoding.Codec
+	lastID string
+	// done receives the result of the stream once it ends.
+	done chan error
+	once sync.Once
+}
+
+// NewEventReader returns a reader of the event stream, the data is decoded by the codec.
+func NewEventReader(body io.ReadCloser, codec encoding.Codec) *EventReader {
+	return &EventReader{body: body, r: bufio.NewReader(body), codec: codec, done: make(chan error, 1)}
+}
+
+// Recv reads the next event, and decodes its data into v if v is not nil.
+// It returns io.EOF when the stream is ended.
+func (r *EventReader) Recv(v interface{}) (*Event, error) {
+	var (
+		e    = &Event{ID: r.lastID}
+		data [][]byte
+		seen bool
+	)
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			r.finish(err)
+			return nil, err
+		}
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		if len(line) == 0 {
+			if !seen {
+				continue
+			}
+			break
+		}
+		if line[0] == ':' {
+			// comment
+			continue
+		}
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "id":
+			e.ID = string(value)
+			r.lastID = e.ID
+		case "event":
+			e.Event = string(value)
+		case "retry":
+			if ms, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				e.Retry = time.Duration(ms) * time.Millisecond
+			}
+		case "data":
+			data = append(data, value)
+		default:
+			// unknown fields are ignored
+			continue
+		}
+		seen = true
+	}
+	raw := bytes.Join(data, []byte("\n"))
+	e.Data = raw
+	if v != nil && len(raw) > 0 {
+		if err := r.codec.Unmarshal(raw, v); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// LastID returns the id of the last event, which can be sent in the Last-Event-ID header on reconnection.
+func (r *EventReader) LastID() string {
+	return r.lastID
+}
+
+// Close closes the event stream.
+func (r *EventReader) Close() error {
+	r.finish(nil)
+	return r.body.Close()
+}
+
+func (r *EventReader) finish(err error) {
+	r.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		r.done <- err
+	})
+}
+
+// Events opens an event stream of the server-sent events. The client timeout is not applied,
+// the stream lasts until the ctx is done, the server ends it, or the reader is closed.
+// The middleware is run until the stream ends, and the ctx derived by it never ends the stream.
+// The data of the events is decoded by the codec of the ContentType call option, or json by default.
+func (client *Client) Events(ctx context.Context, method, path string, args interface{}, opts ...CallOption) (*EventReader, error) {
+	var body io.Reader
+	c := defaultCallInfo(path)
+	for _, o := range opts {
+		if err := o.before(&c); err != nil {
+			return nil, err
+		}
+	}
+	if args != nil {
+		data, err := client.opts.encoder(ctx, c.contentType, args)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	url := fmt.Sprintf("%s://%s%s", client.target.Scheme, client.target.Authority, path)
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if args != nil {
+		req.Header.Set("Content-Type", c.contentType)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if client.opts.userAgent != "" {
+		req.Header.Set("User-Agent", client.opts.userAgent)
+	}
+	ctx = transport.NewClientContext(ctx, &Transport{
+		endpoint:     client.opts.endpoint,
+		reqHeader:    headerCarrier(req.Header),
+		operation:    c.operation,
+		request:      req,
+		pathTemplate: c.pathTemplate,
+	})
+	codec := encoding.GetCodec(httputil.ContentSubtype(c.contentType))
+	if codec == nil {
+		codec = encoding.GetCodec("json")
+	}
+	var m middleware.Middleware
+	if len(client.opts.middleware) > 0 {
+		m = middleware.Chain(client.opts.middleware...)
+	}
+	// the middleware observes the stream until it ends.
+	r, err := istream.Open(ctx, m, args, func(ctx context.Context) (interface{}, <-chan error, error) {
+		r := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, nil, err
+			}
+			r.Body = body
+		}
+		res, err := client.roundTrip(0, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, o := range opts {
+			o.after(&c, &csAttempt{res: res})
+		}
+		er := NewEventReader(res.Body, codec)
+		return er, er.done, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*EventReader), nil
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	istream "github.com/go-kratos/kratos/v2/internal/stream"
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+type testEvent struct {
+	Name string `json:"name"`
+}
+
+func TestEventWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	ew, err := NewEventWriter(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ew.Send(&Event{ID: "1", Event: "update", Retry: time.Second, Data: &testEvent{Name: "kratos"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = ew.Send(&Event{Data: "a\nb"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = ew.Comment("ping"); err != nil {
+		t.Fatal(err)
+	}
+	expect := "id: 1\nevent: update\nretry: 1000\ndata: {\"name\":\"kratos\"}\n\n" +
+		"data: a\ndata: b\n\n" +
+		": ping\n\n"
+	if w.Body.String() != expect {
+		t.Errorf("expect %q, got %q", expect, w.Body.String())
+	}
+	if v := w.Header().Get("Content-Type"); v != "text/event-stream" {
+		t.Errorf("expect %v, got %v", "text/event-stream", v)
+	}
+	if !w.Flushed {
+		t.Errorf("expect flushed")
+	}
+	_ = ew.Close()
+	if err = ew.Send(&Event{Data: "c"}); err != io.ErrClosedPipe {
+		t.Errorf("expect %v, got %v", io.ErrClosedPipe, err)
+	}
+}
+
+func TestEventReader(t *testing.T) {
+	stream := ": comment\n\nid: 1\nevent: update\nretry: 500\ndata: {\"name\":\n" +
+		"data: \"kratos\"}\n\nunknown: x\ndata:plain\r\n\r\n"
+	r := NewEventReader(io.NopCloser(strings.NewReader(stream)), CodecForResponse(&http.Response{Header: http.Header{}}))
+	var v testEvent
+	e, err := r.Recv(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "1" || e.Event != "update" || e.Retry != 500*time.Millisecond || v.Name != "kratos" {
+		t.Errorf("unexpected event %+v %+v", e, v)
+	}
+	e, err = r.Recv(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(e.Data.([]byte)) != "plain" || e.ID != "1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if _, err = r.Recv(nil); err != io.EOF {
+		t.Errorf("expect %v, got %v", io.EOF, err)
+	}
+}
+
+func TestEventWriterClose(t *testing.T) {
+	w := httptest.NewRecorder()
+	ew, err := NewEventWriter(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ew.KeepAlive(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if err = ew.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// nothing is written once closed.
+	n := w.Body.Len()
+	time.Sleep(10 * time.Millisecond)
+	if w.Body.Len() != n {
+		t.Errorf("expect %d bytes, got %d", n, w.Body.Len())
+	}
+	if err = ew.Send(&Event{Data: "a"}); err != io.ErrClosedPipe {
+		t.Errorf("expect %v, got %v", io.ErrClosedPipe, err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	srv := NewServer(RouteTimeout("/events", 0))
+	gone := make(chan struct{})
+	srv.Route("/").GET("/events", func(ctx Context) error {
+		w, err := SSE(ctx)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		w.KeepAlive(10 * time.Millisecond)
+		for i := 0; i < 2; i++ {
+			if err = w.Send(&Event{Event: "update", Data: &testEvent{Name: "kratos"}}); err != nil {
+				return err
+			}
+		}
+		<-w.Done()
+		close(gone)
+		return nil
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ended := make(chan error, 1)
+	client, err := NewClient(context.Background(),
+		WithEndpoint(ts.Listener.Addr().String()),
+		WithTimeout(10*time.Millisecond),
+		WithMiddleware(func(handler middleware.Handler) middleware.Handler {
+			return func(ctx context.Context, req interface{}) (interface{}, error) {
+				// the ctx canceled on return, such as the per-attempt timeout of retries.
+				ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+				defer cancel()
+				_, err := handler(ctx, req)
+				ended <- err
+				return nil, err
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := client.Events(context.Background(), http.MethodGet, "/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		var v testEvent
+		e, err := r.Recv(&v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Event != "update" || v.Name != "kratos" {
+			t.Errorf("unexpected event %+v %+v", e, v)
+		}
+	}
+	// the stream outlives the client timeout with the keepalive.
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err = <-ended:
+		t.Fatalf("expect the middleware to wait for the end of the stream, got %v", err)
+	default:
+	}
+	_ = r.Close()
+	if err = <-ended; err != nil {
+		t.Errorf("expect %v, got %v", nil, err)
+	}
+	select {
+	case <-gone:
+	case <-time.After(time.Second):
+		t.Errorf("expect the disconnect detected")
+	}
+}
+
+func TestEvents_NotOpened(t *testing.T) {
+	client, err := NewClient(context.Background(),
+		WithEndpoint("127.0.0.1:0"),
+		WithMiddleware(func(handler middleware.Handler) middleware.Handler {
+			// the call is merged, e.g. by singleflight.
+			return func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Events(context.Background(), http.MethodGet, "/events", nil); !errors.Is(err, istream.ErrNotOpened) {
+		t.Errorf("expect %v, got %v", istream.ErrNotOpened, err)
+	}
+}
@@ -0,0 +1,40 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RouteTimeouts is the timeouts keyed by the operation or the path template,
+// which can be scanned from config, the values are durations like "30s" or nanoseconds.
+//
+//	timeouts:
+//	  /api.report.v1.Report/Export: 5m
+//	  /v1/files/{name}: 10m
+type RouteTimeouts map[string]time.Duration
+
+// UnmarshalJSON decodes the timeouts of the duration strings or nanoseconds.
+func (t *RouteTimeouts) UnmarshalJSON(data []byte) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	timeouts := make(RouteTimeouts, len(values))
+	for route, v := range values {
+		switch v := v.(type) {
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("transport: invalid timeout of %s: %v", route, err)
+			}
+			timeouts[route] = d
+		case float64:
+			timeouts[route] = time.Duration(v)
+		default:
+			return fmt.Errorf("transport: invalid timeout of %s: %v", route, v)
+		}
+	}
+	*t = timeouts
+	return nil
+}
@@ -25,6 +25,15 @@ type Endpointer interface {
 	Endpoint() (*url.URL, error)
 }
 
+// Drainer is a server which can be drained before it is stopped.
+// 在停止前先将服务标记为不可用，但仍继续处理请求，等待注册中心的变更传播到客户端。
+type Drainer interface {
+	// Drain marks the server as not serving, requests are still handled.
+	Drain(context.Context) error
+	// Inflight returns the number of requests in progress.
+	Inflight() int64
+}
+
 // Header is the storage medium used by a Header.
 type Header interface {
 	Get(key string) string
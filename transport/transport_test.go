@@ -2,8 +2,10 @@ package transport
 
 import (
 	"context"
+	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // mockTransport is a gRPC transport.
@@ -82,3 +84,17 @@ func TestClientTransport(t *testing.T) {
 		t.Errorf("expected:%v got:%v", "test_endpoint", mtr.endpoint)
 	}
 }
+
+func TestRouteTimeouts(t *testing.T) {
+	var timeouts RouteTimeouts
+	if err := json.Unmarshal([]byte(`{"/api.Report/Export":"5m","/v1/files/{name}":1000000000}`), &timeouts); err != nil {
+		t.Fatal(err)
+	}
+	expect := RouteTimeouts{"/api.Report/Export": 5 * time.Minute, "/v1/files/{name}": time.Second}
+	if !reflect.DeepEqual(timeouts, expect) {
+		t.Errorf("expect %v, got %v", expect, timeouts)
+	}
+	if err := json.Unmarshal([]byte(`{"/api.Report/Export":"5x"}`), &timeouts); err == nil {
+		t.Errorf("expect error, got nil")
+	}
+}